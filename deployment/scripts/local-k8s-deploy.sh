@@ -48,16 +48,14 @@ echo -e "${YELLOW}Building Docker images and pushing to minikube...${NC}"
 # Point shell to minikube's Docker daemon
 eval $(minikube docker-env)
 
+# Both images build from the services directory, which holds the shared module
 # Build e2e-app image
 echo -e "${YELLOW}Building e2e-app image...${NC}"
-cd services/e2e-app
-docker build -t e2e-app:latest .
+docker build -t e2e-app:latest -f services/e2e-app/Dockerfile services
 
 # Build e2e-profile image
 echo -e "${YELLOW}Building e2e-profile image...${NC}"
-cd ../e2e-profile
-docker build -t e2e-profile:latest .
-cd ../..
+docker build -t e2e-profile:latest -f services/e2e-profile/Dockerfile services
 
 # Create PostgreSQL secrets
 echo -e "${YELLOW}Creating PostgreSQL secrets...${NC}"
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/tanerincode/e2e-app/internal/rbac"
+	"github.com/tanerincode/e2e-app/internal/repository"
+	"gorm.io/gorm"
+)
+
+// runAccess implements the "access grant|revoke|show" subcommand that manages
+// the roles and permissions of users. Changes show up in access tokens issued
+// from then on, so existing sessions pick them up on their next refresh.
+func runAccess(db *gorm.DB, args []string) {
+	usage := fmt.Sprintf("Usage: %s access grant|revoke -email EMAIL [-role ROLE] [-permission PERMISSION] | show -email EMAIL", os.Args[0])
+	if len(args) == 0 {
+		log.Fatal(usage)
+	}
+
+	flags := flag.NewFlagSet("access "+args[0], flag.ExitOnError)
+	email := flags.String("email", "", "email of the user")
+	role := flags.String("role", "", "role to grant or revoke")
+	permission := flags.String("permission", "", "permission to grant or revoke directly")
+	flags.Parse(args[1:])
+	if *email == "" {
+		log.Fatal(usage)
+	}
+
+	users := repository.NewUserRepository(db)
+	ctx := context.Background()
+
+	user, err := users.GetByEmail(ctx, *email)
+	if err != nil {
+		log.Fatalf("Failed to find user %s: %v", *email, err)
+	}
+
+	switch args[0] {
+	case "grant", "revoke":
+		if *role == "" && *permission == "" {
+			log.Fatal(usage)
+		}
+		grant := args[0] == "grant"
+
+		// Unknown values can still be revoked, e.g. after a role was removed
+		if *role != "" {
+			if !grant {
+				user.RevokeRole(*role)
+			} else if err := rbac.ValidateRole(*role); err != nil {
+				log.Fatal(err)
+			} else {
+				user.GrantRole(*role)
+			}
+		}
+		if *permission != "" {
+			if !grant {
+				user.RevokePermission(*permission)
+			} else if err := rbac.ValidatePermission(*permission); err != nil {
+				log.Fatal(err)
+			} else {
+				user.GrantPermission(*permission)
+			}
+		}
+
+		if err := users.UpdateAccess(ctx, user.ID, user.Roles, user.Permissions); err != nil {
+			log.Fatalf("Failed to update user: %v", err)
+		}
+		printAccess(user.Email, user.GetRoles(), user.GetPermissions())
+	case "show":
+		printAccess(user.Email, user.GetRoles(), user.GetPermissions())
+	default:
+		log.Fatal(usage)
+	}
+}
+
+// printAccess prints a user's roles, direct permissions and the effective
+// permissions their tokens will carry
+func printAccess(email string, roles, permissions []string) {
+	fmt.Printf("user:        %s\n", email)
+	fmt.Printf("roles:       %s\n", strings.Join(roles, " "))
+	fmt.Printf("permissions: %s\n", strings.Join(permissions, " "))
+	fmt.Printf("effective:   %s\n", strings.Join(rbac.Permissions(roles, permissions), " "))
+}
@@ -1,19 +1,30 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net"
-	"sync"
+	"net/http"
+	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/tanerincode/e2e-app/internal/config"
-	auth "github.com/tanerincode/e2e-app/internal/grpc/proto"
+	"github.com/tanerincode/e2e-app/internal/federation"
 	"github.com/tanerincode/e2e-app/internal/grpc/server"
 	"github.com/tanerincode/e2e-app/internal/handler"
+	"github.com/tanerincode/e2e-app/internal/mail"
+	"github.com/tanerincode/e2e-app/internal/rbac"
 	"github.com/tanerincode/e2e-app/internal/repository"
+	"github.com/tanerincode/e2e-app/internal/schema"
 	"github.com/tanerincode/e2e-app/internal/service"
-	"google.golang.org/grpc"
+	"github.com/tanerincode/e2e-app/internal/throttle"
+	"github.com/tanerincode/e2e-pkg/health"
+	"github.com/tanerincode/e2e-pkg/lifecycle"
+	"github.com/tanerincode/e2e-pkg/metrics"
+	"github.com/tanerincode/e2e-pkg/migration"
+	"github.com/tanerincode/e2e-pkg/tracing"
 )
 
 func main() {
@@ -26,67 +37,198 @@ func main() {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	// Handle the migrate subcommand
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if db.Dialector.Name() != "postgres" {
+			log.Fatalf("Migrations only run against PostgreSQL; the mock database creates its schema on startup")
+		}
+		if err := migration.RunCommand(context.Background(), db, schema.Migrations, os.Args[2:], os.Stdout); err != nil {
+			log.Fatalf("Migrate failed: %v", err)
+		}
+		return
+	}
+
+	// Make sure the schema matches this binary. The mock database creates
+	// its schema on startup.
+	if db.Dialector.Name() == "postgres" {
+		if err := migration.Ensure(context.Background(), db, schema.Migrations, cfg.DBAutoMigrate); err != nil {
+			log.Fatalf("Refusing to start: %v", err)
+		}
+	}
+
+	// Handle the oauth-client subcommand
+	if len(os.Args) > 1 && os.Args[1] == "oauth-client" {
+		runOAuthClient(db, os.Args[2:])
+		return
+	}
+
+	// Handle the access subcommand
+	if len(os.Args) > 1 && os.Args[1] == "access" {
+		runAccess(db, os.Args[2:])
+		return
+	}
+
+	// Initialize tracing
+	exporter, err := tracing.NewExporter(context.Background(), cfg.TracingExporter)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	tracerProvider := tracing.Setup(cfg.TracingServiceName, exporter, cfg.GetTracingSampleRatio())
+
+	// Record query timings and trace queries
+	if err := db.Use(metrics.GormPlugin()); err != nil {
+		log.Fatalf("Failed to instrument database: %v", err)
+	}
+	if err := db.Use(tracing.GormPlugin()); err != nil {
+		log.Fatalf("Failed to instrument database: %v", err)
+	}
+
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	revokedTokenRepo := repository.NewRevokedTokenRepository(db)
+	passwordResetRepo := repository.NewPasswordResetTokenRepository(db)
+	recoveryCodeRepo := repository.NewRecoveryCodeRepository(db)
+	oauthClientRepo := repository.NewOAuthClientRepository(db)
+	authorizationCodeRepo := repository.NewAuthorizationCodeRepository(db)
+	linkedIdentityRepo := repository.NewLinkedIdentityRepository(db)
+
+	// Load token signing keys
+	keySet, err := service.NewKeySet(cfg)
+	if err != nil {
+		log.Fatalf("Failed to load signing keys: %v", err)
+	}
+	// ID tokens signed with the shared secret could be forged by any client
+	// able to verify them, so OpenID Connect stays off without a key pair
+	if !keySet.Asymmetric() {
+		log.Printf("OpenID Connect is disabled: set JWT_SIGNING_KEY_FILE to enable it")
+	}
+
+	// Initialize mail sender
+	mailer, err := mail.NewSender(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize mail sender: %v", err)
+	}
+
+	// Initialize login throttling
+	limiter, err := throttle.New(cfg, db)
+	if err != nil {
+		log.Fatalf("Failed to initialize login throttling: %v", err)
+	}
+
+	// Initialize federated login providers
+	federationRegistry, err := federation.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize federated login: %v", err)
+	}
 
 	// Initialize services
-	authService := service.NewAuthService(userRepo, cfg)
-	userService := service.NewUserService(userRepo)
+	authService := service.NewAuthService(userRepo, refreshTokenRepo, revokedTokenRepo, passwordResetRepo, recoveryCodeRepo, oauthClientRepo, authorizationCodeRepo, linkedIdentityRepo, keySet, mailer, limiter, federationRegistry, cfg)
+	userService := service.NewUserService(userRepo, refreshTokenRepo, limiter)
 
 	// Initialize handlers
 	authHandler := handler.NewAuthHandler(authService)
 	userHandler := handler.NewUserHandler(userService)
+	mfaHandler := handler.NewMFAHandler(authService)
+	oauthHandler := handler.NewOAuthHandler(authService)
+	federationHandler := handler.NewFederationHandler(authService)
+	wellKnownHandler := handler.NewWellKnownHandler(keySet, authService)
+	adminHandler := handler.NewAdminHandler(userService, authService)
 
-	// Create a wait group to wait for both servers
-	var wg sync.WaitGroup
-	wg.Add(2)
-
-	// Start gRPC server in a separate goroutine
-	go startGRPCServer(&wg, cfg)
+	// Register the checks readiness depends on
+	checks := health.NewRegistry(cfg.GetHealthCheckTimeout())
+	checks.Register("database", func(ctx context.Context) error { return repository.PingDB(ctx, db) })
+	healthHandler := health.NewHandler(checks)
 
-	// Start HTTP server in a separate goroutine
-	go startHTTPServer(&wg, cfg, authHandler, userHandler)
-
-	// Wait for both servers to finish (which should never happen)
-	wg.Wait()
-}
-
-// startGRPCServer starts the gRPC server
-func startGRPCServer(wg *sync.WaitGroup, cfg *config.Config) {
-	defer wg.Done()
+	// Create both servers
+	grpcServer, err := server.New(cfg, authService, userService, checks)
+	if err != nil {
+		log.Fatalf("Failed to create gRPC server: %v", err)
+	}
+	router := newRouter(cfg, keySet.Asymmetric(), authService, authHandler, userHandler, mfaHandler, oauthHandler, federationHandler, wellKnownHandler, adminHandler, healthHandler)
 
-	// Create listener
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", cfg.GRPCPort))
+	// Open the listeners up front so a port in use fails startup
+	grpcListener, err := net.Listen("tcp", fmt.Sprintf(":%s", cfg.GRPCPort))
 	if err != nil {
 		log.Fatalf("Failed to listen for gRPC: %v", err)
 	}
+	httpListener, err := net.Listen("tcp", ":"+cfg.Port)
+	if err != nil {
+		log.Fatalf("Failed to listen for HTTP: %v", err)
+	}
 
-	// Create gRPC server
-	grpcServer := grpc.NewServer()
-
-	// Register auth service
-	authServer := server.NewAuthServer(cfg)
-	auth.RegisterAuthServiceServer(grpcServer, authServer)
+	// Run both servers until SIGTERM or SIGINT, then drain them and close
+	// the database. Spans are flushed last.
+	manager := lifecycle.New(cfg.GetShutdownTimeout())
+	manager.AddCloser("tracing", func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.GetShutdownTimeout())
+		defer cancel()
+		return tracerProvider.Shutdown(ctx)
+	})
+	manager.AddCloser("database", func() error { return repository.CloseDB(db) })
+	manager.AddServer("gRPC", lifecycle.GRPCServer(grpcServer, grpcListener))
+	manager.AddServer("HTTP", lifecycle.HTTPServer(&http.Server{Handler: router}, httpListener))
+	// Denylist entries are useless once the token they name has expired
+	manager.AddServer("Denylist cleanup", lifecycle.Job("Denylist cleanup", cfg.GetRevokedTokenCleanupInterval(), revokedTokenRepo.DeleteExpired))
+	// Nor are user revocations older than any access token
+	manager.AddServer("Revoked user cleanup", lifecycle.Job("Revoked user cleanup", cfg.GetRevokedTokenCleanupInterval(), func(ctx context.Context) error {
+		return refreshTokenRepo.DeleteRevokedUsers(ctx, time.Now().Add(-cfg.GetJWTExpiration()))
+	}))
+	// Failed logins past their window and lockout count for nothing. The
+	// memory store prunes itself.
+	if cfg.LoginThrottleStore == "database" {
+		loginAttempts := throttle.NewDatabaseStore(db)
+		manager.AddServer("Login attempt cleanup", lifecycle.Job("Login attempt cleanup", cfg.GetRevokedTokenCleanupInterval(), func(ctx context.Context) error {
+			return loginAttempts.DeleteStale(ctx, cfg.GetLoginFailureWindow())
+		}))
+	}
 
 	log.Printf("gRPC server starting on port %s", cfg.GRPCPort)
-	if err := grpcServer.Serve(lis); err != nil {
-		log.Fatalf("Failed to start gRPC server: %v", err)
-	}
+	log.Printf("HTTP server starting on port %s", cfg.Port)
+	os.Exit(manager.Run())
 }
 
-// startHTTPServer starts the HTTP server
-func startHTTPServer(wg *sync.WaitGroup, cfg *config.Config, authHandler *handler.AuthHandler, userHandler *handler.UserHandler) {
-	defer wg.Done()
-
+// newRouter sets up the HTTP routes
+func newRouter(cfg *config.Config, oidcEnabled bool, authService service.AuthService, authHandler *handler.AuthHandler, userHandler *handler.UserHandler, mfaHandler *handler.MFAHandler, oauthHandler *handler.OAuthHandler, federationHandler *handler.FederationHandler, wellKnownHandler *handler.WellKnownHandler, adminHandler *handler.AdminHandler, healthHandler *health.Handler) *gin.Engine {
 	// Setup router
 	r := gin.Default()
+	// Login lockouts are keyed on the client IP, so X-Forwarded-For is only
+	// believed when it comes from a configured proxy
+	if err := r.SetTrustedProxies(cfg.GetTrustedProxies()); err != nil {
+		log.Fatalf("Invalid trusted proxies: %v", err)
+	}
+	r.Use(tracing.HTTPMiddleware(cfg.TracingServiceName))
+	r.Use(metrics.HTTPMiddleware())
 
-	// Health check
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status": "healthy",
-		})
-	})
+	// Health checks. /health is kept for probes that still use it.
+	r.GET("/health", healthHandler.Livez)
+	r.GET("/livez", healthHandler.Livez)
+	r.GET("/readyz", healthHandler.Readyz)
+
+	// Prometheus metrics
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	// Public verification keys
+	r.GET("/.well-known/jwks.json", wellKnownHandler.JWKS)
+
+	// Token introspection for resource servers
+	oauth := r.Group("/oauth")
+	oauth.POST("/introspect", oauthHandler.Introspect)
+
+	// OpenID Connect provider, which needs an asymmetric signing key
+	if oidcEnabled {
+		r.GET("/.well-known/openid-configuration", wellKnownHandler.OpenIDConfiguration)
+		oauth.GET("/authorize", oauthHandler.Authorize)
+		oauth.POST("/authorize", oauthHandler.SubmitAuthorize)
+		oauth.POST("/token", oauthHandler.Token)
+
+		userInfo := r.Group("/userinfo")
+		userInfo.Use(handler.UserInfoMiddleware(authService))
+		{
+			userInfo.GET("", oauthHandler.UserInfo)
+			userInfo.POST("", oauthHandler.UserInfo)
+		}
+	}
 
 	// API routes
 	api := r.Group("/api/v1")
@@ -96,20 +238,59 @@ func startHTTPServer(wg *sync.WaitGroup, cfg *config.Config, authHandler *handle
 		{
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
+			auth.POST("/login/mfa", authHandler.VerifyMFA)
 			auth.POST("/refresh", authHandler.RefreshToken)
+			auth.GET("/verify-email", authHandler.VerifyEmail)
+			auth.POST("/verify-email", authHandler.VerifyEmail)
+			auth.POST("/verify-email/resend", authHandler.ResendVerification)
+			auth.POST("/password/forgot", authHandler.ForgotPassword)
+			auth.POST("/password/reset", authHandler.ResetPassword)
+
+			// Federated login routes
+			auth.GET("/federated", federationHandler.ListProviders)
+			auth.GET("/federated/:provider", federationHandler.Start)
+			auth.GET("/federated/:provider/callback", federationHandler.Callback)
+
+			// Session routes
+			session := auth.Group("")
+			session.Use(handler.AuthMiddleware(authService))
+			{
+				session.POST("/logout", authHandler.Logout)
+				session.POST("/logout-all", authHandler.LogoutAll)
+			}
 		}
 
 		// Protected routes
 		protected := api.Group("/user")
-		protected.Use(handler.AuthMiddleware(cfg))
+		protected.Use(handler.AuthMiddleware(authService))
 		{
 			protected.GET("/profile", userHandler.GetProfile)
+			protected.PATCH("/profile", userHandler.UpdateProfile)
+			protected.POST("/password", userHandler.ChangePassword)
+			protected.DELETE("", userHandler.DeleteAccount)
+			protected.POST("/mfa/totp", mfaHandler.EnrollTOTP)
+			protected.POST("/mfa/totp/confirm", mfaHandler.ConfirmTOTP)
+			protected.DELETE("/mfa/totp", mfaHandler.DisableTOTP)
 		}
-	}
 
-	// Start server
-	log.Printf("HTTP server starting on port %s", cfg.Port)
-	if err := r.Run(":" + cfg.Port); err != nil {
-		log.Fatalf("Failed to start HTTP server: %v", err)
+		// Admin routes
+		admin := api.Group("/admin/users")
+		admin.Use(handler.AuthMiddleware(authService))
+		{
+			admin.GET("", handler.RequirePermission(rbac.PermissionUsersRead), adminHandler.ListUsers)
+			admin.GET("/:id", handler.RequirePermission(rbac.PermissionUsersRead), adminHandler.GetUser)
+
+			manage := admin.Group("")
+			manage.Use(handler.RequirePermission(rbac.PermissionUsersManage))
+			{
+				manage.POST("/:id/disable", adminHandler.DisableUser)
+				manage.POST("/:id/enable", adminHandler.EnableUser)
+				manage.POST("/:id/password-reset", adminHandler.ForcePasswordReset)
+				manage.DELETE("/:id/sessions", adminHandler.RevokeSessions)
+				manage.DELETE("/:id", adminHandler.DeleteUser)
+			}
+		}
 	}
+
+	return r
 }
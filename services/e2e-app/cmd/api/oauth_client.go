@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/tanerincode/e2e-app/internal/repository"
+	"github.com/tanerincode/e2e-app/internal/service"
+	"gorm.io/gorm"
+)
+
+// stringList is a flag that can be given more than once
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// runOAuthClient implements the "oauth-client create|list|delete" subcommand
+// that manages the clients allowed to use the OpenID Connect endpoints
+func runOAuthClient(db *gorm.DB, args []string) {
+	usage := fmt.Sprintf("Usage: %s oauth-client create -name NAME -redirect-uri URI [-redirect-uri URI...] [-public] | list | delete CLIENT_ID", os.Args[0])
+	if len(args) == 0 {
+		log.Fatal(usage)
+	}
+
+	clients := repository.NewOAuthClientRepository(db)
+	ctx := context.Background()
+
+	switch args[0] {
+	case "create":
+		flags := flag.NewFlagSet("oauth-client create", flag.ExitOnError)
+		name := flags.String("name", "", "display name shown on the sign in page")
+		public := flags.Bool("public", false, "register a public client without a secret, e.g. a single page or native app")
+		var redirectURIs stringList
+		flags.Var(&redirectURIs, "redirect-uri", "allowed redirect URI, may be repeated")
+		flags.Parse(args[1:])
+
+		client, secret, err := service.NewOAuthClient(*name, redirectURIs, *public)
+		if err != nil {
+			log.Fatalf("Invalid client: %v", err)
+		}
+		if err := clients.Create(ctx, client); err != nil {
+			log.Fatalf("Failed to register client: %v", err)
+		}
+
+		fmt.Printf("client_id:     %s\n", client.ID)
+		if secret != "" {
+			fmt.Printf("client_secret: %s\n", secret)
+			fmt.Println("Store the secret now, it can't be shown again.")
+		}
+	case "list":
+		list, err := clients.List(ctx)
+		if err != nil {
+			log.Fatalf("Failed to list clients: %v", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CLIENT ID\tNAME\tTYPE\tREDIRECT URIS")
+		for _, client := range list {
+			clientType := "confidential"
+			if client.Public {
+				clientType = "public"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", client.ID, client.Name, clientType, strings.Join(client.GetRedirectURIs(), " "))
+		}
+		w.Flush()
+	case "delete":
+		if len(args) != 2 {
+			log.Fatal(usage)
+		}
+		if err := clients.Delete(ctx, args[1]); err != nil {
+			log.Fatalf("Failed to delete client: %v", err)
+		}
+		log.Printf("Deleted client %s", args[1])
+	default:
+		log.Fatal(usage)
+	}
+}
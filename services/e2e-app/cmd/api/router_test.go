@@ -0,0 +1,433 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanerincode/e2e-app/internal/config"
+	"github.com/tanerincode/e2e-app/internal/federation"
+	"github.com/tanerincode/e2e-app/internal/handler"
+	"github.com/tanerincode/e2e-app/internal/mail"
+	"github.com/tanerincode/e2e-app/internal/model"
+	"github.com/tanerincode/e2e-app/internal/rbac"
+	"github.com/tanerincode/e2e-app/internal/repository"
+	"github.com/tanerincode/e2e-app/internal/service"
+	"github.com/tanerincode/e2e-app/internal/throttle"
+	"github.com/tanerincode/e2e-pkg/health"
+	"gorm.io/gorm"
+)
+
+// testPassword is the password of every user created by testEnv.createUser
+const testPassword = "correct horse battery staple"
+
+const testRedirectURI = "https://client.example/callback"
+
+// testCodeVerifier is the PKCE code verifier authorize sends its challenge for
+var testCodeVerifier = strings.Repeat("v", 43)
+
+// testEnv serves the HTTP routes from newRouter on a fresh mock database
+type testEnv struct {
+	cfg    *config.Config
+	db     *gorm.DB
+	auth   service.AuthService
+	router *gin.Engine
+}
+
+// newTestEnv builds the router the way main does. configure may adjust the
+// configuration before anything is built from it. The mock database is
+// shared by the whole process, so tests must not run in parallel.
+func newTestEnv(t *testing.T, configure ...func(cfg *config.Config)) *testEnv {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.New()
+	cfg.AppEnv = "development"
+	cfg.MockDB = true
+	cfg.LoginThrottleStore = "memory"
+	for _, fn := range configure {
+		fn(cfg)
+	}
+
+	db, err := repository.NewDB(cfg)
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	t.Cleanup(func() { repository.CloseDB(db) })
+
+	keySet, err := service.NewKeySet(cfg)
+	if err != nil {
+		t.Fatalf("failed to load keys: %v", err)
+	}
+	limiter, err := throttle.New(cfg, db)
+	if err != nil {
+		t.Fatalf("failed to create login limiter: %v", err)
+	}
+	registry, err := federation.New(cfg)
+	if err != nil {
+		t.Fatalf("failed to create federation registry: %v", err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	authService := service.NewAuthService(
+		userRepo,
+		refreshTokenRepo,
+		repository.NewRevokedTokenRepository(db),
+		repository.NewPasswordResetTokenRepository(db),
+		repository.NewRecoveryCodeRepository(db),
+		repository.NewOAuthClientRepository(db),
+		repository.NewAuthorizationCodeRepository(db),
+		repository.NewLinkedIdentityRepository(db),
+		keySet,
+		mail.NewLogSender(),
+		limiter,
+		registry,
+		cfg,
+	)
+	userService := service.NewUserService(userRepo, refreshTokenRepo, limiter)
+
+	router := newRouter(cfg, keySet.Asymmetric(), authService,
+		handler.NewAuthHandler(authService),
+		handler.NewUserHandler(userService),
+		handler.NewMFAHandler(authService),
+		handler.NewOAuthHandler(authService),
+		handler.NewFederationHandler(authService),
+		handler.NewWellKnownHandler(keySet, authService),
+		handler.NewAdminHandler(userService, authService),
+		health.NewHandler(health.NewRegistry(time.Second)),
+	)
+	return &testEnv{cfg: cfg, db: db, auth: authService, router: router}
+}
+
+// withSigningKey signs tokens with a fresh Ed25519 key, which OpenID Connect
+// needs
+func withSigningKey(t *testing.T) func(cfg *config.Config) {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to encode key: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "signing.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	return func(cfg *config.Config) {
+		cfg.JWTSigningKeyFile = path
+	}
+}
+
+// do sends a request to the router. A non-nil form is sent URL encoded.
+func (e *testEnv) do(method, target, accessToken string, form url.Values) *httptest.ResponseRecorder {
+	var req *http.Request
+	if form != nil {
+		req = httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		req = httptest.NewRequest(method, target, nil)
+	}
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	rec := httptest.NewRecorder()
+	e.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// createUser registers a user with testPassword
+func (e *testEnv) createUser(t *testing.T, email string) *model.User {
+	t.Helper()
+
+	user := &model.User{
+		Email:     email,
+		Password:  testPassword,
+		FirstName: "Test",
+		LastName:  "User",
+	}
+	if err := e.auth.Register(context.Background(), user); err != nil {
+		t.Fatalf("failed to register %s: %v", email, err)
+	}
+	return user
+}
+
+// login signs a user without two-factor authentication in
+func (e *testEnv) login(t *testing.T, email string) *model.TokenResponse {
+	t.Helper()
+
+	result, err := e.auth.Login(context.Background(), email, testPassword, "192.0.2.1")
+	if err != nil {
+		t.Fatalf("failed to log %s in: %v", email, err)
+	}
+	if result.Tokens == nil {
+		t.Fatalf("login of %s returned no tokens", email)
+	}
+	return result.Tokens
+}
+
+// registerClient registers an OAuth client redirecting to testRedirectURI
+// and returns it along with its secret
+func (e *testEnv) registerClient(t *testing.T, public bool) (*model.OAuthClient, string) {
+	t.Helper()
+
+	client, secret, err := service.NewOAuthClient("Test client", []string{testRedirectURI}, public)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	if err := repository.NewOAuthClientRepository(e.db).Create(context.Background(), client); err != nil {
+		t.Fatalf("failed to register client: %v", err)
+	}
+	return client, secret
+}
+
+// clientLogin signs a user in for a new public OAuth client through
+// /oauth/authorize and /oauth/token, asking for scope, and returns the
+// client's tokens
+func (e *testEnv) clientLogin(t *testing.T, email, scope string) *model.TokenResponse {
+	t.Helper()
+
+	client, _ := e.registerClient(t, true)
+	return e.clientLoginAs(t, client, "", email, scope)
+}
+
+// clientLoginAs is clientLogin for a registered client and its secret
+func (e *testEnv) clientLoginAs(t *testing.T, client *model.OAuthClient, secret, email, scope string) *model.TokenResponse {
+	t.Helper()
+
+	sum := sha256.Sum256([]byte(testCodeVerifier))
+	rec := e.do(http.MethodPost, "/oauth/authorize", "", url.Values{
+		"response_type":         {"code"},
+		"client_id":             {client.ID},
+		"redirect_uri":          {testRedirectURI},
+		"scope":                 {scope},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+		"email":                 {email},
+		"password":              {testPassword},
+	})
+	if rec.Code != http.StatusFound {
+		t.Fatalf("authorize: got status %d, want %d: %s", rec.Code, http.StatusFound, rec.Body)
+	}
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil || location.Query().Get("code") == "" {
+		t.Fatalf("authorize redirected to %q, want a code", rec.Header().Get("Location"))
+	}
+
+	rec = e.do(http.MethodPost, "/oauth/token", "", url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {location.Query().Get("code")},
+		"redirect_uri":  {testRedirectURI},
+		"code_verifier": {testCodeVerifier},
+		"client_id":     {client.ID},
+		"client_secret": {secret},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("token: got status %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var tokens model.TokenResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &tokens); err != nil {
+		t.Fatalf("failed to decode token response: %v", err)
+	}
+	return &tokens
+}
+
+func TestClientTokensOnlyOpenUserInfo(t *testing.T) {
+	env := newTestEnv(t, withSigningKey(t))
+	user := env.createUser(t, "admin@example.com")
+	if err := repository.NewUserRepository(env.db).UpdateAccess(context.Background(), user.ID, rbac.RoleAdmin, ""); err != nil {
+		t.Fatalf("failed to grant admin: %v", err)
+	}
+	tokens := env.clientLogin(t, "admin@example.com", "openid")
+
+	if rec := env.do(http.MethodGet, "/userinfo", tokens.AccessToken, nil); rec.Code != http.StatusOK {
+		t.Errorf("/userinfo: got status %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+
+	// The token belongs to an admin, but was only issued for /userinfo
+	for _, target := range []string{"/api/v1/user/profile", "/api/v1/admin/users"} {
+		rec := env.do(http.MethodGet, target, tokens.AccessToken, nil)
+		if rec.Code != http.StatusUnauthorized && rec.Code != http.StatusForbidden {
+			t.Errorf("%s: got status %d, want %d or %d", target, rec.Code, http.StatusUnauthorized, http.StatusForbidden)
+		}
+	}
+
+	// The same user's first-party token opens all of them
+	accessToken := env.login(t, "admin@example.com").AccessToken
+	for _, target := range []string{"/userinfo", "/api/v1/user/profile", "/api/v1/admin/users"} {
+		if rec := env.do(http.MethodGet, target, accessToken, nil); rec.Code != http.StatusOK {
+			t.Errorf("%s with a first-party token: got status %d, want %d", target, rec.Code, http.StatusOK)
+		}
+	}
+}
+
+func TestOpenIDConnectNeedsAsymmetricKey(t *testing.T) {
+	env := newTestEnv(t)
+
+	for _, target := range []string{"/.well-known/openid-configuration", "/oauth/authorize", "/userinfo"} {
+		if rec := env.do(http.MethodGet, target, "", nil); rec.Code != http.StatusNotFound {
+			t.Errorf("%s without a signing key: got status %d, want %d", target, rec.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestAdminRoutesNeedPermission(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	users := repository.NewUserRepository(env.db)
+	target := env.createUser(t, "target@example.com")
+	env.createUser(t, "user@example.com")
+	reader := env.createUser(t, "reader@example.com")
+	if err := users.UpdateAccess(ctx, reader.ID, "", rbac.PermissionUsersRead); err != nil {
+		t.Fatalf("failed to grant users:read: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		email  string
+		method string
+		target string
+		want   int
+	}{
+		{name: "plain user lists users", email: "user@example.com", method: http.MethodGet, target: "/api/v1/admin/users", want: http.StatusForbidden},
+		{name: "plain user disables a user", email: "user@example.com", method: http.MethodPost, target: "/api/v1/admin/users/" + target.ID.String() + "/disable", want: http.StatusForbidden},
+		{name: "reader lists users", email: "reader@example.com", method: http.MethodGet, target: "/api/v1/admin/users", want: http.StatusOK},
+		{name: "reader disables a user", email: "reader@example.com", method: http.MethodPost, target: "/api/v1/admin/users/" + target.ID.String() + "/disable", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(tt.method, tt.target, env.login(t, tt.email).AccessToken, nil)
+			if rec.Code != tt.want {
+				t.Errorf("got status %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+
+	if rec := env.do(http.MethodGet, "/api/v1/admin/users", "", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("without a token: got status %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+// introspect posts a token to /oauth/introspect as a confidential client
+func (e *testEnv) introspect(t *testing.T, client *model.OAuthClient, secret, token, hint string) model.TokenIntrospection {
+	t.Helper()
+
+	rec := e.do(http.MethodPost, "/oauth/introspect", "", url.Values{
+		"token":           {token},
+		"token_type_hint": {hint},
+		"client_id":       {client.ID},
+		"client_secret":   {secret},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("introspect: got status %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var result model.TokenIntrospection
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to decode introspection response: %v", err)
+	}
+	return result
+}
+
+func TestIntrospection(t *testing.T) {
+	env := newTestEnv(t, withSigningKey(t))
+	ctx := context.Background()
+	env.createUser(t, "user@example.com")
+
+	client, secret := env.registerClient(t, false)
+	otherClient, otherSecret := env.registerClient(t, false)
+	resourceServer, resourceServerSecret := env.registerClient(t, false)
+	env.cfg.OAuthIntrospectionClients = resourceServer.ID
+
+	clientTokens := env.clientLoginAs(t, client, secret, "user@example.com", "openid")
+	otherTokens := env.clientLoginAs(t, otherClient, otherSecret, "user@example.com", "openid")
+	firstParty := env.login(t, "user@example.com")
+	loggedOut := env.login(t, "user@example.com")
+	claims, err := env.auth.ValidateAccessToken(ctx, loggedOut.AccessToken)
+	if err != nil {
+		t.Fatalf("failed to validate access token: %v", err)
+	}
+	if err := env.auth.Logout(ctx, claims); err != nil {
+		t.Fatalf("logout failed: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		client     *model.OAuthClient
+		secret     string
+		token      string
+		hint       string
+		wantActive bool
+		wantType   string
+	}{
+		{name: "own access token", client: client, secret: secret, token: clientTokens.AccessToken, wantActive: true, wantType: service.TokenTypeAccess},
+		{name: "own refresh token", client: client, secret: secret, token: clientTokens.RefreshToken, hint: service.TokenTypeRefresh, wantActive: true, wantType: service.TokenTypeRefresh},
+		{name: "own refresh token without a hint", client: client, secret: secret, token: clientTokens.RefreshToken, wantActive: true, wantType: service.TokenTypeRefresh},
+		{name: "other client's token", client: client, secret: secret, token: otherTokens.AccessToken},
+		{name: "first-party token", client: client, secret: secret, token: firstParty.AccessToken},
+		{name: "resource server, other client's token", client: resourceServer, secret: resourceServerSecret, token: otherTokens.AccessToken, wantActive: true, wantType: service.TokenTypeAccess},
+		{name: "resource server, first-party token", client: resourceServer, secret: resourceServerSecret, token: firstParty.AccessToken, wantActive: true, wantType: service.TokenTypeAccess},
+		{name: "resource server, revoked token", client: resourceServer, secret: resourceServerSecret, token: loggedOut.AccessToken},
+		{name: "resource server, revoked refresh token", client: resourceServer, secret: resourceServerSecret, token: loggedOut.RefreshToken, hint: service.TokenTypeRefresh},
+		{name: "garbage", client: resourceServer, secret: resourceServerSecret, token: "not-a-token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := env.introspect(t, tt.client, tt.secret, tt.token, tt.hint)
+			if result.Active != tt.wantActive || result.TokenType != tt.wantType {
+				t.Fatalf("got active %v, type %q, want active %v, type %q", result.Active, result.TokenType, tt.wantActive, tt.wantType)
+			}
+			if !result.Active && (result.Subject != "" || result.ClientID != "") {
+				t.Errorf("inactive token described as %+v", result)
+			}
+		})
+	}
+
+	// Public clients can't introspect at all
+	publicClient, _ := env.registerClient(t, true)
+	rec := env.do(http.MethodPost, "/oauth/introspect", "", url.Values{
+		"token":     {clientTokens.AccessToken},
+		"client_id": {publicClient.ID},
+	})
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("public client: got status %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestRefreshRejectsAccessToken(t *testing.T) {
+	env := newTestEnv(t)
+	env.createUser(t, "user@example.com")
+	tokens := env.login(t, "user@example.com")
+
+	refresh := func(token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", nil)
+		req.Header.Set("X-Refresh-Token", token)
+		rec := httptest.NewRecorder()
+		env.router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := refresh(tokens.AccessToken); code != http.StatusUnauthorized {
+		t.Errorf("access token: got status %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := refresh(tokens.RefreshToken); code != http.StatusOK {
+		t.Errorf("refresh token: got status %d, want %d", code, http.StatusOK)
+	}
+}
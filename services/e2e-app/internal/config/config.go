@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,6 +16,26 @@ type Config struct {
 	// HTTP Server
 	Port string
 
+	// TrustedProxies lists the addresses or CIDR ranges of the proxies whose
+	// X-Forwarded-For header is believed when working out the client IP.
+	// Empty trusts none, so the client IP is the peer address.
+	TrustedProxies string
+
+	// ShutdownTimeout bounds how long in-flight HTTP requests and gRPC calls
+	// may take to finish after SIGTERM
+	ShutdownTimeout string
+
+	// HealthCheckTimeout bounds each dependency check behind /readyz and the
+	// gRPC health service
+	HealthCheckTimeout string
+
+	// Tracing. TracingExporter is "none", "otlp", "stdout" or "memory"; the
+	// OTLP exporter reads its endpoint from the standard
+	// OTEL_EXPORTER_OTLP_* variables.
+	TracingExporter    string
+	TracingServiceName string
+	TracingSampleRatio string
+
 	// Database
 	DBHost     string
 	DBPort     string
@@ -22,14 +43,99 @@ type Config struct {
 	DBPassword string
 	DBName     string
 
+	// Migrations
+	DBAutoMigrate bool
+
 	// JWT
 	JWTSecret         string
 	JWTExpiration     string
 	RefreshSecret     string
 	RefreshExpiration string
 
+	// RevokedTokenCleanupInterval is how often expired entries are removed
+	// from the access token denylist and the other tables that only grow:
+	// revoked users and, with the database throttle store, login attempts
+	RevokedTokenCleanupInterval string
+
+	// Token audiences. Access tokens are issued for every audience in
+	// JWTAudience; this service only accepts the ones that name
+	// JWTExpectedAudience.
+	JWTAudience         string
+	JWTExpectedAudience string
+
+	// JWT signing keys. When JWTSigningKeyFile is empty access tokens are
+	// signed with HS256 using JWTSecret.
+	JWTSigningKeyFile       string
+	JWTVerificationKeyFiles string
+
+	// Email verification
+	PublicURL                   string
+	EmailVerificationSecret     string
+	EmailVerificationExpiration string
+	RequireEmailVerification    bool
+
+	// Password reset
+	PasswordResetURL        string
+	PasswordResetExpiration string
+
+	// OpenID Connect provider. Clients can only introspect the tokens issued
+	// to them, except for the resource servers in OAuthIntrospectionClients.
+	OAuthCodeExpiration       string
+	OAuthIntrospectionClients string
+
+	// Federated login through upstream OpenID Connect providers
+	FederatedProviders        []FederatedProvider
+	FederationStateSecret     string
+	FederationStateExpiration string
+
+	// Two-factor authentication
+	MFAIssuer              string
+	MFAChallengeSecret     string
+	MFAChallengeExpiration string
+
+	// Login throttling
+	LoginThrottleStore      string
+	LoginMaxAccountFailures int
+	LoginMaxIPFailures      int
+	LoginFailureWindow      string
+	LoginLockoutBase        string
+	LoginLockoutMax         string
+
+	// Mail
+	MailDriver   string
+	MailDir      string
+	MailFrom     string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+
 	// gRPC
 	GRPCPort string
+
+	// gRPC TLS. The server uses TLS when a certificate and key are set and
+	// requires client certificates signed by GRPCTLSClientCAFile when that
+	// is set too. GRPCTLSAllowedClients optionally restricts which client
+	// certificate identities may connect.
+	GRPCTLSCertFile       string
+	GRPCTLSKeyFile        string
+	GRPCTLSClientCAFile   string
+	GRPCTLSAllowedClients string
+
+	// ServiceCredentials maps the credential of each service allowed to call
+	// the internal gRPC services to the service's name
+	ServiceCredentials map[string]string
+}
+
+// FederatedProvider is an upstream OpenID Connect provider users can sign in
+// with. Each provider named in FEDERATED_PROVIDERS is configured through
+// FEDERATED_<NAME>_* variables, e.g. FEDERATED_GOOGLE_ISSUER_URL.
+type FederatedProvider struct {
+	Name         string
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
 }
 
 // New creates a new Config with values from environment or defaults
@@ -39,7 +145,16 @@ func New() *Config {
 		AppEnv: getEnv("APP_ENV", "development"),
 		MockDB: getBoolEnv("MOCK_DB", false),
 
-		Port: getEnv("PORT", "8080"),
+		Port:            getEnv("PORT", "8080"),
+		TrustedProxies:  getEnv("TRUSTED_PROXIES", ""),
+		ShutdownTimeout: getEnv("SHUTDOWN_TIMEOUT", "20s"),
+
+		HealthCheckTimeout: getEnv("HEALTH_CHECK_TIMEOUT", "2s"),
+
+		// Tracing settings
+		TracingExporter:    getEnv("TRACING_EXPORTER", "none"),
+		TracingServiceName: getEnv("OTEL_SERVICE_NAME", "e2e-app"),
+		TracingSampleRatio: getEnv("TRACING_SAMPLE_RATIO", "1"),
 
 		// Database settings
 		DBHost:     getEnv("DB_HOST", "localhost"),
@@ -48,14 +163,81 @@ func New() *Config {
 		DBPassword: getEnv("DB_PASSWORD", "postgres"),
 		DBName:     getEnv("DB_NAME", "e2e_app"),
 
+		// Migration settings
+		DBAutoMigrate: getBoolEnv("DB_AUTO_MIGRATE", false),
+
 		// JWT settings
 		JWTSecret:         getEnv("JWT_SECRET", "your-secret-key"),
 		JWTExpiration:     getEnv("JWT_EXPIRATION", "24h"),
 		RefreshSecret:     getEnv("REFRESH_SECRET", "your-refresh-secret-key"),
 		RefreshExpiration: getEnv("REFRESH_EXPIRATION", "168h"),
 
+		RevokedTokenCleanupInterval: getEnv("REVOKED_TOKEN_CLEANUP_INTERVAL", "1h"),
+
+		// Token audience settings - the issued audiences are comma separated
+		// and should name every service that accepts the tokens
+		JWTAudience:         getEnv("JWT_AUDIENCE", "e2e-app,e2e-profile"),
+		JWTExpectedAudience: getEnv("JWT_EXPECTED_AUDIENCE", "e2e-app"),
+
+		// JWT signing key settings
+		JWTSigningKeyFile:       getEnv("JWT_SIGNING_KEY_FILE", ""),
+		JWTVerificationKeyFiles: getEnv("JWT_VERIFICATION_KEY_FILES", ""),
+
+		// Email verification settings
+		PublicURL:                   getEnv("PUBLIC_URL", "http://localhost:8080"),
+		EmailVerificationSecret:     getEnv("EMAIL_VERIFICATION_SECRET", "your-verification-secret-key"),
+		EmailVerificationExpiration: getEnv("EMAIL_VERIFICATION_EXPIRATION", "24h"),
+		RequireEmailVerification:    getBoolEnv("REQUIRE_EMAIL_VERIFICATION", false),
+
+		// Password reset settings - the URL is the page users land on from the email
+		PasswordResetURL:        getEnv("PASSWORD_RESET_URL", "http://localhost:8080/reset-password"),
+		PasswordResetExpiration: getEnv("PASSWORD_RESET_EXPIRATION", "1h"),
+
+		// OpenID Connect settings - PublicURL doubles as the issuer
+		OAuthCodeExpiration:       getEnv("OAUTH_CODE_EXPIRATION", "1m"),
+		OAuthIntrospectionClients: getEnv("OAUTH_INTROSPECTION_CLIENTS", ""),
+
+		// Federated login settings
+		FederatedProviders:        getFederatedProviders(),
+		FederationStateSecret:     getEnv("FEDERATION_STATE_SECRET", "your-federation-state-secret-key"),
+		FederationStateExpiration: getEnv("FEDERATION_STATE_EXPIRATION", "10m"),
+
+		// Two-factor authentication settings - the issuer is the name shown in
+		// authenticator apps
+		MFAIssuer:              getEnv("MFA_ISSUER", "e2e-app"),
+		MFAChallengeSecret:     getEnv("MFA_CHALLENGE_SECRET", "your-mfa-challenge-secret-key"),
+		MFAChallengeExpiration: getEnv("MFA_CHALLENGE_EXPIRATION", "5m"),
+
+		// Login throttling settings - use the "database" store when running
+		// more than one replica
+		LoginThrottleStore:      getEnv("LOGIN_THROTTLE_STORE", "memory"),
+		LoginMaxAccountFailures: getIntEnv("LOGIN_MAX_ACCOUNT_FAILURES", 5),
+		LoginMaxIPFailures:      getIntEnv("LOGIN_MAX_IP_FAILURES", 20),
+		LoginFailureWindow:      getEnv("LOGIN_FAILURE_WINDOW", "15m"),
+		LoginLockoutBase:        getEnv("LOGIN_LOCKOUT_BASE", "1m"),
+		LoginLockoutMax:         getEnv("LOGIN_LOCKOUT_MAX", "1h"),
+
+		// Mail settings - "log" and "file" are meant for local runs
+		MailDriver:   getEnv("MAIL_DRIVER", "log"),
+		MailDir:      getEnv("MAIL_DIR", "./mail"),
+		MailFrom:     getEnv("MAIL_FROM", "no-reply@e2e-app.local"),
+		SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:     getEnv("SMTP_PORT", "25"),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+
 		// gRPC settings
 		GRPCPort: getEnv("GRPC_PORT", "50051"),
+
+		// gRPC TLS settings - allowed clients are comma separated DNS names,
+		// URIs or common names of client certificates
+		GRPCTLSCertFile:       getEnv("GRPC_TLS_CERT_FILE", ""),
+		GRPCTLSKeyFile:        getEnv("GRPC_TLS_KEY_FILE", ""),
+		GRPCTLSClientCAFile:   getEnv("GRPC_TLS_CLIENT_CA_FILE", ""),
+		GRPCTLSAllowedClients: getEnv("GRPC_TLS_ALLOWED_CLIENTS", ""),
+
+		// Service credentials as comma separated name:credential pairs
+		ServiceCredentials: getServiceCredentials(),
 	}
 }
 
@@ -68,6 +250,34 @@ func (c *Config) GetJWTExpiration() time.Duration {
 	return duration
 }
 
+// GetShutdownTimeout returns the parsed shutdown drain timeout
+func (c *Config) GetShutdownTimeout() time.Duration {
+	duration, err := time.ParseDuration(c.ShutdownTimeout)
+	if err != nil {
+		return 20 * time.Second // Default to 20 seconds
+	}
+	return duration
+}
+
+// GetHealthCheckTimeout returns the parsed timeout of a single health check
+func (c *Config) GetHealthCheckTimeout() time.Duration {
+	duration, err := time.ParseDuration(c.HealthCheckTimeout)
+	if err != nil {
+		return 2 * time.Second // Default to 2 seconds
+	}
+	return duration
+}
+
+// GetTracingSampleRatio returns the share of new traces to sample, between
+// 0 and 1
+func (c *Config) GetTracingSampleRatio() float64 {
+	ratio, err := strconv.ParseFloat(c.TracingSampleRatio, 64)
+	if err != nil || ratio < 0 || ratio > 1 {
+		return 1 // Default to sampling every trace
+	}
+	return ratio
+}
+
 // GetRefreshExpiration returns the parsed refresh token expiration duration
 func (c *Config) GetRefreshExpiration() time.Duration {
 	duration, err := time.ParseDuration(c.RefreshExpiration)
@@ -77,6 +287,183 @@ func (c *Config) GetRefreshExpiration() time.Duration {
 	return duration
 }
 
+// GetRevokedTokenCleanupInterval returns how often expired denylist entries,
+// revoked users and login attempts are removed
+func (c *Config) GetRevokedTokenCleanupInterval() time.Duration {
+	duration, err := time.ParseDuration(c.RevokedTokenCleanupInterval)
+	if err != nil || duration <= 0 {
+		return time.Hour // Default to 1 hour
+	}
+	return duration
+}
+
+// GetEmailVerificationExpiration returns the parsed email verification token expiration duration
+func (c *Config) GetEmailVerificationExpiration() time.Duration {
+	duration, err := time.ParseDuration(c.EmailVerificationExpiration)
+	if err != nil {
+		return 24 * time.Hour // Default to 24 hours
+	}
+	return duration
+}
+
+// GetPasswordResetExpiration returns the parsed password reset token expiration duration
+func (c *Config) GetPasswordResetExpiration() time.Duration {
+	duration, err := time.ParseDuration(c.PasswordResetExpiration)
+	if err != nil {
+		return time.Hour // Default to 1 hour
+	}
+	return duration
+}
+
+// GetOAuthCodeExpiration returns how long an authorization code can be redeemed
+func (c *Config) GetOAuthCodeExpiration() time.Duration {
+	duration, err := time.ParseDuration(c.OAuthCodeExpiration)
+	if err != nil {
+		return time.Minute // Default to 1 minute
+	}
+	return duration
+}
+
+// GetFederationStateExpiration returns how long a user may take to sign in at
+// an upstream provider
+func (c *Config) GetFederationStateExpiration() time.Duration {
+	duration, err := time.ParseDuration(c.FederationStateExpiration)
+	if err != nil {
+		return 10 * time.Minute // Default to 10 minutes
+	}
+	return duration
+}
+
+// GetMFAChallengeExpiration returns how long the second login step may take
+func (c *Config) GetMFAChallengeExpiration() time.Duration {
+	duration, err := time.ParseDuration(c.MFAChallengeExpiration)
+	if err != nil {
+		return 5 * time.Minute // Default to 5 minutes
+	}
+	return duration
+}
+
+// GetLoginFailureWindow returns how long failed logins are remembered
+func (c *Config) GetLoginFailureWindow() time.Duration {
+	duration, err := time.ParseDuration(c.LoginFailureWindow)
+	if err != nil {
+		return 15 * time.Minute // Default to 15 minutes
+	}
+	return duration
+}
+
+// GetLoginLockoutBase returns the parsed duration of the first lockout
+func (c *Config) GetLoginLockoutBase() time.Duration {
+	duration, err := time.ParseDuration(c.LoginLockoutBase)
+	if err != nil {
+		return time.Minute // Default to 1 minute
+	}
+	return duration
+}
+
+// GetLoginLockoutMax returns the parsed upper bound for lockouts
+func (c *Config) GetLoginLockoutMax() time.Duration {
+	duration, err := time.ParseDuration(c.LoginLockoutMax)
+	if err != nil {
+		return time.Hour // Default to 1 hour
+	}
+	return duration
+}
+
+// GetJWTVerificationKeyFiles returns the paths of the additional keys access
+// tokens may be verified with, such as the previous signing key during rotation
+func (c *Config) GetJWTVerificationKeyFiles() []string {
+	var files []string
+	for _, file := range strings.Split(c.JWTVerificationKeyFiles, ",") {
+		if file = strings.TrimSpace(file); file != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// GetJWTAudience returns the audiences access tokens are issued for
+func (c *Config) GetJWTAudience() []string {
+	var audiences []string
+	for _, audience := range strings.Split(c.JWTAudience, ",") {
+		if audience = strings.TrimSpace(audience); audience != "" {
+			audiences = append(audiences, audience)
+		}
+	}
+	return audiences
+}
+
+// GetOAuthIntrospectionClients returns the OAuth clients that may introspect
+// tokens issued to any client
+func (c *Config) GetOAuthIntrospectionClients() []string {
+	var clients []string
+	for _, client := range strings.Split(c.OAuthIntrospectionClients, ",") {
+		if client = strings.TrimSpace(client); client != "" {
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
+
+// GetTrustedProxies returns the proxies whose forwarding headers are trusted
+func (c *Config) GetTrustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(c.TrustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+// GetGRPCTLSAllowedClients returns the client certificate identities allowed
+// to call the gRPC server. Empty means any certificate the client CA signed.
+func (c *Config) GetGRPCTLSAllowedClients() []string {
+	var clients []string
+	for _, client := range strings.Split(c.GRPCTLSAllowedClients, ",") {
+		if client = strings.TrimSpace(client); client != "" {
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
+
+// getFederatedProviders reads the providers named in FEDERATED_PROVIDERS
+func getFederatedProviders() []FederatedProvider {
+	var providers []FederatedProvider
+	for _, name := range strings.Split(getEnv("FEDERATED_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "FEDERATED_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		providers = append(providers, FederatedProvider{
+			Name:         name,
+			IssuerURL:    getEnv(prefix+"ISSUER_URL", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			Scopes:       strings.Fields(getEnv(prefix+"SCOPES", "openid email profile")),
+		})
+	}
+	return providers
+}
+
+// getServiceCredentials reads SERVICE_CREDENTIALS, a comma separated list
+// of name:credential pairs, e.g. "e2e-profile:s3cret". Entries without a
+// name or credential are skipped.
+func getServiceCredentials() map[string]string {
+	credentials := make(map[string]string)
+	for _, entry := range strings.Split(getEnv("SERVICE_CREDENTIALS", ""), ",") {
+		name, credential, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || name == "" || credential == "" {
+			continue
+		}
+		credentials[credential] = name
+	}
+	return credentials
+}
+
 // Helper to get environment variable with fallback
 func getEnv(key, fallback string) string {
 	if value, exists := os.LookupEnv(key); exists {
@@ -96,3 +483,15 @@ func getBoolEnv(key string, fallback bool) bool {
 	}
 	return fallback
 }
+
+// Helper to get integer environment variable with fallback
+func getIntEnv(key string, fallback int) int {
+	if value, exists := os.LookupEnv(key); exists {
+		parsedValue, err := strconv.Atoi(value)
+		if err != nil {
+			return fallback
+		}
+		return parsedValue
+	}
+	return fallback
+}
\ No newline at end of file
@@ -0,0 +1,47 @@
+package federation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tanerincode/e2e-app/internal/config"
+)
+
+// Registry holds the configured upstream providers by name
+type Registry struct {
+	providers map[string]*Provider
+	names     []string
+}
+
+// New returns a registry of the providers in cfg.FederatedProviders. Users
+// are sent back to /api/v1/auth/federated/<name>/callback under cfg.PublicURL,
+// which is the redirect URI to register with each provider.
+func New(cfg *config.Config) (*Registry, error) {
+	registry := &Registry{providers: make(map[string]*Provider)}
+	for _, providerCfg := range cfg.FederatedProviders {
+		if _, exists := registry.providers[providerCfg.Name]; exists {
+			return nil, fmt.Errorf("federated provider %q is configured twice", providerCfg.Name)
+		}
+
+		redirectURL := strings.TrimRight(cfg.PublicURL, "/") + "/api/v1/auth/federated/" + providerCfg.Name + "/callback"
+		provider, err := NewProvider(providerCfg, redirectURL)
+		if err != nil {
+			return nil, err
+		}
+
+		registry.providers[providerCfg.Name] = provider
+		registry.names = append(registry.names, providerCfg.Name)
+	}
+	return registry, nil
+}
+
+// Get returns the provider with the given name
+func (r *Registry) Get(name string) (*Provider, bool) {
+	provider, ok := r.providers[name]
+	return provider, ok
+}
+
+// Names returns the names of the configured providers in configuration order
+func (r *Registry) Names() []string {
+	return r.names
+}
@@ -2,25 +2,26 @@ package server
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"time"
 
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/tanerincode/e2e-app/internal/config"
-	pb "github.com/tanerincode/e2e-app/internal/grpc/proto"
+	"github.com/tanerincode/e2e-app/internal/service"
+	pb "github.com/tanerincode/e2e-pkg/grpc/proto"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // AuthServer implements the gRPC auth service for token validation
 type AuthServer struct {
 	pb.UnimplementedAuthServiceServer
-	config *config.Config
+	authService service.AuthService
 }
 
 // NewAuthServer creates a new auth gRPC server
-func NewAuthServer(cfg *config.Config) *AuthServer {
+func NewAuthServer(authService service.AuthService) *AuthServer {
 	return &AuthServer{
-		config: cfg,
+		authService: authService,
 	}
 }
 
@@ -36,27 +37,49 @@ func (s *AuthServer) ValidateToken(ctx context.Context, req *pb.TokenRequest) (*
 		}, nil
 	}
 
-	// Parse token
-	token, err := jwt.Parse(req.Token, func(token *jwt.Token) (interface{}, error) {
-		// Validate algorithm
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(s.config.JWTSecret), nil
-	})
-
+	// Verify the token for the caller's audience, or our own if the caller
+	// didn't name one, and check it against the revocation list
+	var claims *service.AccessClaims
+	var err error
+	if req.Audience == "" {
+		claims, err = s.authService.ValidateAccessToken(ctx, req.Token)
+	} else {
+		claims, err = s.authService.ValidateAccessTokenFor(ctx, req.Token, req.Audience)
+	}
 	if err != nil {
-		return &pb.TokenResponse{
-			Valid: false,
-			Error: &pb.Error{
-				Code:    "token_parsing_failed",
-				Message: err.Error(),
-			},
-		}, nil
+		switch {
+		case errors.Is(err, service.ErrTokenRevoked):
+			return &pb.TokenResponse{
+				Valid: false,
+				Error: &pb.Error{
+					Code:    "token_revoked",
+					Message: "Token has been revoked",
+				},
+			}, nil
+		case errors.Is(err, service.ErrAccountDisabled):
+			return &pb.TokenResponse{
+				Valid: false,
+				Error: &pb.Error{
+					Code:    "account_disabled",
+					Message: "Account is disabled",
+				},
+			}, nil
+		case errors.Is(err, service.ErrInvalidToken):
+			return &pb.TokenResponse{
+				Valid: false,
+				Error: &pb.Error{
+					Code:    "invalid_token",
+					Message: "Token is invalid",
+				},
+			}, nil
+		default:
+			return nil, status.Error(codes.Internal, "failed to validate token")
+		}
 	}
 
-	// Validate token
-	if !token.Valid {
+	// Tokens issued to OAuth clients are only good at /userinfo, whatever
+	// audience the caller asks for
+	if claims.ClientID != "" {
 		return &pb.TokenResponse{
 			Valid: false,
 			Error: &pb.Error{
@@ -66,15 +89,8 @@ func (s *AuthServer) ValidateToken(ctx context.Context, req *pb.TokenRequest) (*
 		}, nil
 	}
 
-	// Extract claims
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return nil, status.Error(codes.Internal, "failed to parse token claims")
-	}
-
 	// Get user ID
-	userID, ok := claims["user_id"].(string)
-	if !ok {
+	if claims.UserID == "" {
 		return &pb.TokenResponse{
 			Valid: false,
 			Error: &pb.Error{
@@ -84,12 +100,78 @@ func (s *AuthServer) ValidateToken(ctx context.Context, req *pb.TokenRequest) (*
 		}, nil
 	}
 
-	// Get email (if available)
-	email, _ := claims["email"].(string)
-
 	return &pb.TokenResponse{
-		Valid:  true,
-		UserId: userID,
-		Email:  email,
+		Valid:       true,
+		UserId:      claims.UserID,
+		Amr:         claims.AMR,
+		Roles:       claims.Roles,
+		Permissions: claims.Permissions,
 	}, nil
 }
+
+// IntrospectToken describes an access or refresh token
+func (s *AuthServer) IntrospectToken(ctx context.Context, req *pb.IntrospectRequest) (*pb.IntrospectResponse, error) {
+	if req.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is empty")
+	}
+
+	result, err := s.authService.IntrospectToken(ctx, req.Token, req.TokenTypeHint)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to introspect token")
+	}
+	if !result.Active {
+		return &pb.IntrospectResponse{Active: false}, nil
+	}
+
+	resp := &pb.IntrospectResponse{
+		Active:      true,
+		TokenType:   result.TokenType,
+		Subject:     result.Subject,
+		Username:    result.Username,
+		Issuer:      result.Issuer,
+		Audience:    result.Audience,
+		TokenId:     result.TokenID,
+		SessionId:   result.SessionID,
+		ClientId:    result.ClientID,
+		Scope:       result.Scope,
+		Amr:         result.AMR,
+		Roles:       result.Roles,
+		Permissions: result.Permissions,
+	}
+	if result.IssuedAt != 0 {
+		resp.IssuedAt = timestamppb.New(time.Unix(result.IssuedAt, 0))
+	}
+	if result.ExpiresAt != 0 {
+		resp.ExpiresAt = timestamppb.New(time.Unix(result.ExpiresAt, 0))
+	}
+	return resp, nil
+}
+
+// ListRevocations returns the revocations of access tokens that haven't
+// expired yet
+func (s *AuthServer) ListRevocations(ctx context.Context, req *pb.ListRevocationsRequest) (*pb.ListRevocationsResponse, error) {
+	revocations, err := s.authService.Revocations(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list revocations")
+	}
+
+	resp := &pb.ListRevocationsResponse{
+		TokenIds:    make([]string, 0, len(revocations.TokenIDs)),
+		SessionIds:  make([]string, 0, len(revocations.SessionIDs)),
+		Users:       make([]*pb.RevokedUser, 0, len(revocations.Users)),
+		GeneratedAt: timestamppb.New(revocations.GeneratedAt),
+	}
+	for _, id := range revocations.TokenIDs {
+		resp.TokenIds = append(resp.TokenIds, id.String())
+	}
+	for _, id := range revocations.SessionIDs {
+		resp.SessionIds = append(resp.SessionIds, id.String())
+	}
+	for _, user := range revocations.Users {
+		resp.Users = append(resp.Users, &pb.RevokedUser{
+			UserId:    user.UserID.String(),
+			RevokedAt: timestamppb.New(user.RevokedAt),
+		})
+	}
+	return resp, nil
+}
@@ -0,0 +1,208 @@
+package server
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tanerincode/e2e-app/internal/config"
+	"github.com/tanerincode/e2e-app/internal/totp"
+	pb "github.com/tanerincode/e2e-pkg/grpc/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestValidateTokenReportsRevocation(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	env.createUser(t, "revoked@example.com")
+	loggedOut := env.login(t, "revoked@example.com")
+	active := env.login(t, "revoked@example.com")
+
+	claims, err := env.auth.ValidateAccessToken(ctx, loggedOut.AccessToken)
+	if err != nil {
+		t.Fatalf("failed to validate access token: %v", err)
+	}
+	if err := env.auth.Logout(ctx, claims); err != nil {
+		t.Fatalf("logout failed: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		token     string
+		wantValid bool
+		wantCode  string
+	}{
+		{name: "logged out session", token: loggedOut.AccessToken, wantCode: "token_revoked"},
+		{name: "other session", token: active.AccessToken, wantValid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := env.authClient().ValidateToken(ctx, &pb.TokenRequest{Token: tt.token})
+			if err != nil {
+				t.Fatalf("ValidateToken failed: %v", err)
+			}
+			if resp.Valid != tt.wantValid || resp.GetError().GetCode() != tt.wantCode {
+				t.Errorf("got valid %v, error %q, want valid %v, error %q", resp.Valid, resp.GetError().GetCode(), tt.wantValid, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestValidateTokenRejectsOtherTokenTypes(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) {
+		// With one secret for every kind of token only the claims tell
+		// them apart
+		cfg.RefreshSecret = cfg.JWTSecret
+		cfg.MFAChallengeSecret = cfg.JWTSecret
+	})
+	ctx := context.Background()
+
+	env.createUser(t, "plain@example.com")
+	tokens := env.login(t, "plain@example.com")
+	mfaUser := env.createUser(t, "mfa@example.com")
+	enrollment, err := env.auth.EnrollTOTP(ctx, mfaUser.ID)
+	if err != nil {
+		t.Fatalf("failed to enroll TOTP: %v", err)
+	}
+	code, err := totp.Code(enrollment.Secret, time.Now())
+	if err != nil {
+		t.Fatalf("failed to generate TOTP code: %v", err)
+	}
+	if _, err := env.auth.ConfirmTOTP(ctx, mfaUser.ID, code); err != nil {
+		t.Fatalf("failed to confirm TOTP: %v", err)
+	}
+	login, err := env.auth.Login(ctx, "mfa@example.com", testPassword, "192.0.2.1")
+	if err != nil || login.Challenge == nil {
+		t.Fatalf("login of mfa@example.com didn't ask for a second factor: %v", err)
+	}
+
+	for name, token := range map[string]string{"refresh token": tokens.RefreshToken, "MFA challenge": login.Challenge.MFAToken} {
+		for _, audience := range []string{"", "e2e-profile"} {
+			resp, err := env.authClient().ValidateToken(ctx, &pb.TokenRequest{Token: token, Audience: audience})
+			if err != nil {
+				t.Fatalf("ValidateToken failed: %v", err)
+			}
+			if resp.Valid || resp.GetError().GetCode() != "invalid_token" {
+				t.Errorf("%s for audience %q: got valid %v, error %q, want invalid_token", name, audience, resp.Valid, resp.GetError().GetCode())
+			}
+		}
+	}
+
+	resp, err := env.authClient().ValidateToken(ctx, &pb.TokenRequest{Token: tokens.AccessToken})
+	if err != nil || !resp.Valid {
+		t.Errorf("access token: got %v, %v, want it valid", resp, err)
+	}
+}
+
+func TestListRevocations(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	env.createUser(t, "logged-out@example.com")
+	loggedOut := env.login(t, "logged-out@example.com")
+	active := env.login(t, "logged-out@example.com")
+	deleted := env.createUser(t, "deleted@example.com")
+	env.login(t, "deleted@example.com")
+
+	loggedOutClaims, err := env.auth.ValidateAccessToken(ctx, loggedOut.AccessToken)
+	if err != nil {
+		t.Fatalf("failed to validate access token: %v", err)
+	}
+	activeClaims, err := env.auth.ValidateAccessToken(ctx, active.AccessToken)
+	if err != nil {
+		t.Fatalf("failed to validate access token: %v", err)
+	}
+	if err := env.auth.Logout(ctx, loggedOutClaims); err != nil {
+		t.Fatalf("logout failed: %v", err)
+	}
+	// Deleting the user removes its refresh tokens, the revocation stays
+	if err := env.users.RemoveUser(ctx, uuid.New(), deleted.ID); err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+
+	if _, err := env.authClient().ListRevocations(serviceContext(""), &pb.ListRevocationsRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("without a credential: got %v, want %v", status.Code(err), codes.Unauthenticated)
+	}
+
+	resp, err := env.authClient().ListRevocations(serviceContext(testCredential), &pb.ListRevocationsRequest{})
+	if err != nil {
+		t.Fatalf("ListRevocations failed: %v", err)
+	}
+	if !slices.Contains(resp.TokenIds, loggedOutClaims.ID) {
+		t.Errorf("token ids %v miss the logged out token %s", resp.TokenIds, loggedOutClaims.ID)
+	}
+	if !slices.Contains(resp.SessionIds, loggedOutClaims.SessionID) {
+		t.Errorf("session ids %v miss the logged out session %s", resp.SessionIds, loggedOutClaims.SessionID)
+	}
+	if slices.Contains(resp.TokenIds, activeClaims.ID) || slices.Contains(resp.SessionIds, activeClaims.SessionID) {
+		t.Errorf("the active session is listed as revoked")
+	}
+	if len(resp.Users) != 1 || resp.Users[0].UserId != deleted.ID.String() {
+		t.Fatalf("got revoked users %v, want only %s", resp.Users, deleted.ID)
+	}
+	if revokedAt := resp.Users[0].RevokedAt.AsTime(); revokedAt.After(resp.GeneratedAt.AsTime()) {
+		t.Errorf("user revoked at %v, after the list was generated at %v", revokedAt, resp.GeneratedAt.AsTime())
+	}
+}
+
+func TestIntrospectToken(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	user := env.createUser(t, "user@example.com")
+	active := env.login(t, "user@example.com")
+	loggedOut := env.login(t, "user@example.com")
+	claims, err := env.auth.ValidateAccessToken(ctx, loggedOut.AccessToken)
+	if err != nil {
+		t.Fatalf("failed to validate access token: %v", err)
+	}
+	if err := env.auth.Logout(ctx, claims); err != nil {
+		t.Fatalf("logout failed: %v", err)
+	}
+
+	if _, err := env.authClient().IntrospectToken(serviceContext(""), &pb.IntrospectRequest{Token: active.AccessToken}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("without credential: got %v, want %v", err, codes.Unauthenticated)
+	}
+	if _, err := env.authClient().IntrospectToken(serviceContext(testCredential), &pb.IntrospectRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("empty token: got %v, want %v", err, codes.InvalidArgument)
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		hint       string
+		wantActive bool
+		wantType   string
+	}{
+		{name: "access token", token: active.AccessToken, wantActive: true, wantType: "access_token"},
+		{name: "refresh token", token: active.RefreshToken, hint: "refresh_token", wantActive: true, wantType: "refresh_token"},
+		{name: "refresh token without a hint", token: active.RefreshToken, wantActive: true, wantType: "refresh_token"},
+		{name: "revoked access token", token: loggedOut.AccessToken},
+		{name: "revoked refresh token", token: loggedOut.RefreshToken, hint: "refresh_token"},
+		{name: "garbage", token: "not-a-token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := env.authClient().IntrospectToken(serviceContext(testCredential), &pb.IntrospectRequest{
+				Token:         tt.token,
+				TokenTypeHint: tt.hint,
+			})
+			if err != nil {
+				t.Fatalf("IntrospectToken failed: %v", err)
+			}
+			if resp.Active != tt.wantActive || resp.TokenType != tt.wantType {
+				t.Fatalf("got active %v, type %q, want active %v, type %q", resp.Active, resp.TokenType, tt.wantActive, tt.wantType)
+			}
+			if resp.Active && (resp.Subject != user.ID.String() || resp.SessionId == "" || resp.ExpiresAt == nil) {
+				t.Errorf("active token described as %+v", resp)
+			}
+			if !resp.Active && resp.Subject != "" {
+				t.Errorf("inactive token described as %+v", resp)
+			}
+		})
+	}
+}
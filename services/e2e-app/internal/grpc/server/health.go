@@ -0,0 +1,40 @@
+package server
+
+import (
+	"context"
+
+	pb "github.com/tanerincode/e2e-pkg/grpc/proto"
+	"github.com/tanerincode/e2e-pkg/health"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// HealthServer implements the standard grpc.health.v1 service on top of the
+// readiness checks. The empty service name stands for the whole server. Watch
+// isn't supported; probes only call Check.
+type HealthServer struct {
+	healthpb.UnimplementedHealthServer
+	checks *health.Registry
+}
+
+// NewHealthServer creates a health server that reports readiness from checks
+func NewHealthServer(checks *health.Registry) *HealthServer {
+	return &HealthServer{
+		checks: checks,
+	}
+}
+
+// Check reports SERVING when every readiness check passes
+func (s *HealthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	switch req.Service {
+	case "", pb.AuthService_ServiceDesc.ServiceName, pb.UserService_ServiceDesc.ServiceName:
+	default:
+		return nil, status.Error(codes.NotFound, "unknown service")
+	}
+
+	if !s.checks.Run(ctx).Healthy() {
+		return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_NOT_SERVING}, nil
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
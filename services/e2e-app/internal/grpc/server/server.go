@@ -0,0 +1,53 @@
+package server
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/tanerincode/e2e-app/internal/config"
+	"github.com/tanerincode/e2e-app/internal/service"
+	pb "github.com/tanerincode/e2e-pkg/grpc/proto"
+	"github.com/tanerincode/e2e-pkg/health"
+	"github.com/tanerincode/e2e-pkg/metrics"
+	"github.com/tanerincode/e2e-pkg/tracing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// New creates the gRPC server with the auth, user and health services. Other
+// services need a credential from cfg.ServiceCredentials to read users, and
+// the server uses TLS when cfg names a certificate.
+func New(cfg *config.Config, authService service.AuthService, userService *service.UserService, checks *health.Registry) (*grpc.Server, error) {
+	if len(cfg.ServiceCredentials) == 0 {
+		log.Printf("No SERVICE_CREDENTIALS configured, the gRPC UserService and token introspection will reject every call")
+	}
+	opts := []grpc.ServerOption{
+		grpc.StatsHandler(tracing.GRPCServerHandler()),
+		grpc.ChainUnaryInterceptor(
+			metrics.UnaryServerInterceptor(),
+			ServiceAuthInterceptor(cfg.ServiceCredentials),
+		),
+	}
+
+	// Serve over TLS, verifying client certificates if configured
+	tlsConfig, err := TLSConfig(cfg.GRPCTLSCertFile, cfg.GRPCTLSKeyFile, cfg.GRPCTLSClientCAFile, cfg.GetGRPCTLSAllowedClients())
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure gRPC TLS: %w", err)
+	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		if tlsConfig.ClientCAs != nil {
+			log.Printf("gRPC server requires client certificates")
+		}
+	}
+	grpcServer := grpc.NewServer(opts...)
+
+	pb.RegisterAuthServiceServer(grpcServer, NewAuthServer(authService))
+	pb.RegisterUserServiceServer(grpcServer, NewUserServer(userService))
+
+	// Health service for gRPC probes
+	healthpb.RegisterHealthServer(grpcServer, NewHealthServer(checks))
+
+	return grpcServer, nil
+}
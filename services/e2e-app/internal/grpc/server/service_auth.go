@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"strings"
+
+	pb "github.com/tanerincode/e2e-pkg/grpc/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// callerServiceKey is the context key of the name of the calling service
+type callerServiceKey struct{}
+
+// CallerService returns the name of the service whose credential came with
+// the call. It is only set for calls ServiceAuthInterceptor protects.
+func CallerService(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(callerServiceKey{}).(string)
+	return name, ok
+}
+
+// ServiceAuthInterceptor requires a service credential for calls to the
+// UserService, to token introspection and to the revocation list. Callers send it as
+// "authorization: Bearer <credential>" metadata. ValidateToken stays open,
+// since the token it validates is the caller's proof already. When no
+// credentials are configured every protected call is rejected. The name of
+// the calling service is added to the context, see CallerService.
+func ServiceAuthInterceptor(credentials map[string]string) grpc.UnaryServerInterceptor {
+	userServicePrefix := "/" + pb.UserService_ServiceDesc.ServiceName + "/"
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		protected := strings.HasPrefix(info.FullMethod, userServicePrefix) ||
+			info.FullMethod == pb.AuthService_IntrospectToken_FullMethodName ||
+			info.FullMethod == pb.AuthService_ListRevocations_FullMethodName
+		if !protected {
+			return handler(ctx, req)
+		}
+
+		caller, ok := serviceCaller(ctx, credentials)
+		if !ok {
+			log.Printf("Rejected %s without a valid service credential", info.FullMethod)
+			return nil, status.Error(codes.Unauthenticated, "a valid service credential is required")
+		}
+		return handler(context.WithValue(ctx, callerServiceKey{}, caller), req)
+	}
+}
+
+// serviceCaller returns the name of the service whose configured credential
+// came with the call
+func serviceCaller(ctx context.Context, credentials map[string]string) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	for _, value := range md.Get("authorization") {
+		presented, found := strings.CutPrefix(value, "Bearer ")
+		if !found || presented == "" {
+			continue
+		}
+		// Compare against every credential so the time taken doesn't tell
+		// which one came closest
+		caller, matched := "", false
+		for credential, name := range credentials {
+			if subtle.ConstantTimeCompare([]byte(presented), []byte(credential)) == 1 {
+				caller, matched = name, true
+			}
+		}
+		if matched {
+			return caller, true
+		}
+	}
+	return "", false
+}
\ No newline at end of file
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/tanerincode/e2e-app/internal/config"
+	"github.com/tanerincode/e2e-app/internal/federation"
+	"github.com/tanerincode/e2e-app/internal/mail"
+	"github.com/tanerincode/e2e-app/internal/model"
+	"github.com/tanerincode/e2e-app/internal/repository"
+	"github.com/tanerincode/e2e-app/internal/service"
+	"github.com/tanerincode/e2e-app/internal/throttle"
+	pb "github.com/tanerincode/e2e-pkg/grpc/proto"
+	"github.com/tanerincode/e2e-pkg/health"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// testCredential is the credential the test service calls with
+const testCredential = "test-service-credential"
+
+// testPassword is the password of every user created by testEnv.createUser
+const testPassword = "correct horse battery staple"
+
+// testEnv runs the gRPC server on a fresh mock database
+type testEnv struct {
+	cfg   *config.Config
+	auth  service.AuthService
+	users *service.UserService
+	conn  *grpc.ClientConn
+}
+
+// newTestEnv starts the server with New on an in-memory connection. The
+// service called "test-service" may call it with testCredential.
+// configure may adjust the configuration before anything is built from it.
+// The mock database is shared by the whole process, so tests must not run
+// in parallel.
+func newTestEnv(t *testing.T, configure ...func(cfg *config.Config)) *testEnv {
+	t.Helper()
+
+	cfg := config.New()
+	cfg.AppEnv = "development"
+	cfg.MockDB = true
+	cfg.LoginThrottleStore = "memory"
+	cfg.ServiceCredentials = map[string]string{testCredential: "test-service"}
+	for _, fn := range configure {
+		fn(cfg)
+	}
+
+	db, err := repository.NewDB(cfg)
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	t.Cleanup(func() { repository.CloseDB(db) })
+
+	keys, err := service.NewKeySet(cfg)
+	if err != nil {
+		t.Fatalf("failed to load keys: %v", err)
+	}
+	limiter, err := throttle.New(cfg, db)
+	if err != nil {
+		t.Fatalf("failed to create login limiter: %v", err)
+	}
+	registry, err := federation.New(cfg)
+	if err != nil {
+		t.Fatalf("failed to create federation registry: %v", err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	authService := service.NewAuthService(
+		userRepo,
+		refreshTokenRepo,
+		repository.NewRevokedTokenRepository(db),
+		repository.NewPasswordResetTokenRepository(db),
+		repository.NewRecoveryCodeRepository(db),
+		repository.NewOAuthClientRepository(db),
+		repository.NewAuthorizationCodeRepository(db),
+		repository.NewLinkedIdentityRepository(db),
+		keys,
+		mail.NewLogSender(),
+		limiter,
+		registry,
+		cfg,
+	)
+	userService := service.NewUserService(userRepo, refreshTokenRepo, limiter)
+
+	grpcServer, err := New(cfg, authService, userService, health.NewRegistry(time.Second))
+	if err != nil {
+		t.Fatalf("failed to create gRPC server: %v", err)
+	}
+	listener := bufconn.Listen(1 << 20)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///e2e-app",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return &testEnv{cfg: cfg, auth: authService, users: userService, conn: conn}
+}
+
+// serviceContext returns a context that calls with credential, or none if
+// it is empty
+func serviceContext(credential string) context.Context {
+	ctx := context.Background()
+	if credential == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+credential)
+}
+
+func (e *testEnv) authClient() pb.AuthServiceClient {
+	return pb.NewAuthServiceClient(e.conn)
+}
+
+func (e *testEnv) userClient() pb.UserServiceClient {
+	return pb.NewUserServiceClient(e.conn)
+}
+
+// createUser registers a user with testPassword
+func (e *testEnv) createUser(t *testing.T, email string) *model.User {
+	t.Helper()
+
+	user := &model.User{
+		Email:     email,
+		Password:  testPassword,
+		FirstName: "Test",
+		LastName:  "User",
+	}
+	if err := e.auth.Register(context.Background(), user); err != nil {
+		t.Fatalf("failed to register %s: %v", email, err)
+	}
+	return user
+}
+
+// login signs a user without two-factor authentication in
+func (e *testEnv) login(t *testing.T, email string) *model.TokenResponse {
+	t.Helper()
+
+	result, err := e.auth.Login(context.Background(), email, testPassword, "192.0.2.1")
+	if err != nil {
+		t.Fatalf("failed to log %s in: %v", email, err)
+	}
+	if result.Tokens == nil {
+		t.Fatalf("login of %s returned no tokens", email)
+	}
+	return result.Tokens
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// TLSConfig builds the TLS configuration of the gRPC server. It returns nil
+// when no certificate is configured, in which case the server runs in
+// plaintext. With a client CA, callers must present a certificate it signed,
+// and with allowedClients that certificate must also carry one of the listed
+// identities.
+func TLSConfig(certFile, keyFile, clientCAFile string, allowedClients []string) (*tls.Config, error) {
+	if certFile == "" && keyFile == "" {
+		if clientCAFile != "" || len(allowedClients) > 0 {
+			return nil, errors.New("gRPC client certificate checks need a server certificate and key")
+		}
+		return nil, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load gRPC server certificate: %w", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if clientCAFile == "" {
+		if len(allowedClients) > 0 {
+			return nil, errors.New("restricting gRPC clients needs a client CA")
+		}
+		return config, nil
+	}
+
+	pem, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gRPC client CA: %w", err)
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in gRPC client CA %s", clientCAFile)
+	}
+	config.ClientCAs = clientCAs
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+
+	if len(allowedClients) > 0 {
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return errors.New("no client certificate")
+			}
+			if !isAllowedClient(state.PeerCertificates[0], allowedClients) {
+				return fmt.Errorf("client certificate %q is not allowed", state.PeerCertificates[0].Subject.CommonName)
+			}
+			return nil
+		}
+	}
+	return config, nil
+}
+
+// isAllowedClient reports whether one of the identities of the certificate,
+// its DNS names, URIs or common name, is in the allowed list
+func isAllowedClient(cert *x509.Certificate, allowedClients []string) bool {
+	identities := append([]string{cert.Subject.CommonName}, cert.DNSNames...)
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+
+	for _, identity := range identities {
+		for _, allowed := range allowedClients {
+			if identity != "" && identity == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tanerincode/e2e-pkg/health"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// serverName is the name the server certificate is issued for
+const serverName = "e2e-app.test"
+
+// testCA issues the server and client certificates of a test
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+	dir  string
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate CA key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "e2e test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create CA certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse CA certificate: %v", err)
+	}
+
+	ca := &testCA{cert: cert, key: key, pool: x509.NewCertPool(), dir: t.TempDir()}
+	ca.pool.AddCert(cert)
+	writePEM(t, filepath.Join(ca.dir, "ca.crt"), "CERTIFICATE", der)
+	return ca
+}
+
+// issue creates a certificate for name and writes it and its key to files,
+// returning their paths
+func (ca *testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key for %s: %v", name, err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatalf("failed to generate serial number: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("failed to create certificate for %s: %v", name, err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to encode key for %s: %v", name, err)
+	}
+
+	certFile := filepath.Join(ca.dir, name+".crt")
+	keyFile := filepath.Join(ca.dir, name+".key")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+// startTLSServer serves the health service with the TLS configuration of
+// TLSConfig, allowing only the e2e-profile client
+func startTLSServer(t *testing.T, ca *testCA) *bufconn.Listener {
+	t.Helper()
+
+	certFile, keyFile := ca.issue(t, serverName, x509.ExtKeyUsageServerAuth)
+	tlsConfig, err := TLSConfig(certFile, keyFile, filepath.Join(ca.dir, "ca.crt"), []string{"e2e-profile"})
+	if err != nil {
+		t.Fatalf("failed to configure TLS: %v", err)
+	}
+
+	grpcServer := grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsConfig)))
+	healthpb.RegisterHealthServer(grpcServer, NewHealthServer(health.NewRegistry(time.Second)))
+	listener := bufconn.Listen(1 << 20)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+	return listener
+}
+
+func TestTLSClientCertificates(t *testing.T) {
+	ca := newTestCA(t)
+	listener := startTLSServer(t, ca)
+
+	// clientTLS connects with a certificate for name, or none if name is empty
+	clientTLS := func(t *testing.T, name string) grpc.DialOption {
+		config := &tls.Config{ServerName: serverName, RootCAs: ca.pool, MinVersion: tls.VersionTLS12}
+		if name != "" {
+			certificate, err := tls.LoadX509KeyPair(ca.issue(t, name, x509.ExtKeyUsageClientAuth))
+			if err != nil {
+				t.Fatalf("failed to load client certificate: %v", err)
+			}
+			config.Certificates = []tls.Certificate{certificate}
+		}
+		return grpc.WithTransportCredentials(credentials.NewTLS(config))
+	}
+
+	tests := []struct {
+		name  string
+		creds func(t *testing.T) grpc.DialOption
+		// wantCode is the code of a call refused during the handshake
+		wantCode codes.Code
+	}{
+		{
+			name:     "plaintext client",
+			creds:    func(t *testing.T) grpc.DialOption { return grpc.WithTransportCredentials(insecure.NewCredentials()) },
+			wantCode: codes.Unavailable,
+		},
+		{
+			name:     "no client certificate",
+			creds:    func(t *testing.T) grpc.DialOption { return clientTLS(t, "") },
+			wantCode: codes.Unavailable,
+		},
+		{
+			name:     "client certificate not allowed",
+			creds:    func(t *testing.T) grpc.DialOption { return clientTLS(t, "e2e-intruder") },
+			wantCode: codes.Unavailable,
+		},
+		{
+			name:  "allowed client certificate",
+			creds: func(t *testing.T) grpc.DialOption { return clientTLS(t, "e2e-profile") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := grpc.NewClient("passthrough:///"+serverName,
+				grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+					return listener.DialContext(ctx)
+				}),
+				tt.creds(t),
+			)
+			if err != nil {
+				t.Fatalf("failed to create client: %v", err)
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("got %v (%v), want %v", got, err, tt.wantCode)
+			}
+			if err != nil {
+				return
+			}
+			if resp.Status != healthpb.HealthCheckResponse_SERVING {
+				t.Errorf("got status %v, want SERVING", resp.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/tanerincode/e2e-app/internal/model"
+	"github.com/tanerincode/e2e-app/internal/repository"
+	"github.com/tanerincode/e2e-app/internal/service"
+	pb "github.com/tanerincode/e2e-pkg/grpc/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// maxBatchGetUsers caps the IDs of a single BatchGetUsers call
+	maxBatchGetUsers = 100
+	// maxListUsersPageSize caps the page size of ListUsers, as the admin API does
+	maxListUsersPageSize = 100
+)
+
+// UserServer implements the gRPC user service other services read user
+// accounts through
+type UserServer struct {
+	pb.UnimplementedUserServiceServer
+	userService *service.UserService
+}
+
+// NewUserServer creates a new user gRPC server
+func NewUserServer(userService *service.UserService) *UserServer {
+	return &UserServer{
+		userService: userService,
+	}
+}
+
+// GetUser returns a single user
+func (s *UserServer) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.User, error) {
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid user ID")
+	}
+
+	user, err := s.userService.GetUserByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, status.Error(codes.Internal, "failed to get user")
+	}
+
+	return toProtoUser(user), nil
+}
+
+// BatchGetUsers returns the users with the given IDs. IDs without a user are
+// reported back instead of failing the whole call.
+func (s *UserServer) BatchGetUsers(ctx context.Context, req *pb.BatchGetUsersRequest) (*pb.BatchGetUsersResponse, error) {
+	if len(req.Ids) > maxBatchGetUsers {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("at most %d IDs can be requested at once", maxBatchGetUsers))
+	}
+
+	ids := make([]uuid.UUID, 0, len(req.Ids))
+	seen := make(map[uuid.UUID]bool, len(req.Ids))
+	for _, rawID := range req.Ids {
+		id, err := uuid.Parse(rawID)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("invalid user ID %q", rawID))
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	users, err := s.userService.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to get users")
+	}
+
+	found := make(map[uuid.UUID]*model.User, len(users))
+	for _, user := range users {
+		found[user.ID] = user
+	}
+
+	// Users come back in the order they were asked for
+	resp := &pb.BatchGetUsersResponse{Users: make([]*pb.User, 0, len(users))}
+	for _, id := range ids {
+		if user, ok := found[id]; ok {
+			resp.Users = append(resp.Users, toProtoUser(user))
+		} else {
+			resp.MissingIds = append(resp.MissingIds, id.String())
+		}
+	}
+	return resp, nil
+}
+
+// ListUsers returns a page of users, optionally filtered by email, creation
+// date and status
+func (s *UserServer) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	if req.Page < 0 {
+		return nil, status.Error(codes.InvalidArgument, "page must be at least 1")
+	}
+	if req.PageSize < 0 || req.PageSize > maxListUsersPageSize {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("page_size must be between 1 and %d", maxListUsersPageSize))
+	}
+
+	listReq := &model.ListUsersRequest{
+		Email:    req.Email,
+		Disabled: req.Disabled,
+		Page:     int(req.Page),
+		PageSize: int(req.PageSize),
+	}
+	if req.CreatedAfter != nil {
+		createdAfter := req.CreatedAfter.AsTime()
+		listReq.CreatedAfter = &createdAfter
+	}
+	if req.CreatedBefore != nil {
+		createdBefore := req.CreatedBefore.AsTime()
+		listReq.CreatedBefore = &createdBefore
+	}
+
+	page, err := s.userService.ListUsers(ctx, listReq)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list users")
+	}
+
+	// Listing exports accounts in bulk, so keep a record of who did it
+	caller, _ := CallerService(ctx)
+	log.Printf("Service %s listed users (page %d, %d of %d)", caller, page.Page, len(page.Users), page.Total)
+
+	resp := &pb.ListUsersResponse{
+		Users:    make([]*pb.User, 0, len(page.Users)),
+		Total:    page.Total,
+		Page:     int32(page.Page),
+		PageSize: int32(page.PageSize),
+	}
+	for _, user := range page.Users {
+		resp.Users = append(resp.Users, toProtoUser(user))
+	}
+	return resp, nil
+}
+
+func toProtoUser(user *model.User) *pb.User {
+	return &pb.User{
+		Id:            user.ID.String(),
+		Email:         user.Email,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		EmailVerified: user.EmailVerified,
+		Disabled:      user.Disabled,
+		Roles:         user.GetRoles(),
+		CreatedAt:     timestamppb.New(user.CreatedAt),
+		UpdatedAt:     timestamppb.New(user.UpdatedAt),
+	}
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	pb "github.com/tanerincode/e2e-pkg/grpc/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUserServiceRequiresCredential(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t, "user@example.com")
+
+	for _, credential := range []string{"", "wrong-credential"} {
+		ctx := serviceContext(credential)
+		if _, err := env.userClient().GetUser(ctx, &pb.GetUserRequest{Id: user.ID.String()}); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("GetUser with credential %q: got %v, want %v", credential, err, codes.Unauthenticated)
+		}
+		if _, err := env.userClient().BatchGetUsers(ctx, &pb.BatchGetUsersRequest{Ids: []string{user.ID.String()}}); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("BatchGetUsers with credential %q: got %v, want %v", credential, err, codes.Unauthenticated)
+		}
+		if _, err := env.userClient().ListUsers(ctx, &pb.ListUsersRequest{}); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("ListUsers with credential %q: got %v, want %v", credential, err, codes.Unauthenticated)
+		}
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := serviceContext(testCredential)
+	user := env.createUser(t, "user@example.com")
+
+	got, err := env.userClient().GetUser(ctx, &pb.GetUserRequest{Id: user.ID.String()})
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if got.Id != user.ID.String() || got.Email != user.Email {
+		t.Errorf("got user %s (%s), want %s (%s)", got.Id, got.Email, user.ID, user.Email)
+	}
+
+	if _, err := env.userClient().GetUser(ctx, &pb.GetUserRequest{Id: uuid.NewString()}); status.Code(err) != codes.NotFound {
+		t.Errorf("unknown user: got %v, want %v", err, codes.NotFound)
+	}
+	if _, err := env.userClient().GetUser(ctx, &pb.GetUserRequest{Id: "not-a-uuid"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("invalid ID: got %v, want %v", err, codes.InvalidArgument)
+	}
+}
+
+func TestBatchGetUsers(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := serviceContext(testCredential)
+	first := env.createUser(t, "first@example.com")
+	second := env.createUser(t, "second@example.com")
+	missing := uuid.NewString()
+
+	resp, err := env.userClient().BatchGetUsers(ctx, &pb.BatchGetUsersRequest{
+		Ids: []string{second.ID.String(), missing, first.ID.String(), second.ID.String()},
+	})
+	if err != nil {
+		t.Fatalf("BatchGetUsers failed: %v", err)
+	}
+	// Users come back once each, in the order they were asked for
+	var got []string
+	for _, user := range resp.Users {
+		got = append(got, user.Id)
+	}
+	if want := []string{second.ID.String(), first.ID.String()}; !slices.Equal(got, want) {
+		t.Errorf("got users %v, want %v", got, want)
+	}
+	if want := []string{missing}; !slices.Equal(resp.MissingIds, want) {
+		t.Errorf("got missing IDs %v, want %v", resp.MissingIds, want)
+	}
+
+	t.Run("batch size", func(t *testing.T) {
+		ids := make([]string, maxBatchGetUsers+1)
+		for i := range ids {
+			ids[i] = uuid.NewString()
+		}
+
+		resp, err := env.userClient().BatchGetUsers(ctx, &pb.BatchGetUsersRequest{Ids: ids[:maxBatchGetUsers]})
+		if err != nil {
+			t.Fatalf("%d IDs: %v", maxBatchGetUsers, err)
+		}
+		if len(resp.MissingIds) != maxBatchGetUsers {
+			t.Errorf("%d IDs: got %d missing, want all of them", maxBatchGetUsers, len(resp.MissingIds))
+		}
+		if _, err := env.userClient().BatchGetUsers(ctx, &pb.BatchGetUsersRequest{Ids: ids}); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%d IDs: got %v, want %v", len(ids), err, codes.InvalidArgument)
+		}
+	})
+
+	t.Run("invalid ID", func(t *testing.T) {
+		_, err := env.userClient().BatchGetUsers(ctx, &pb.BatchGetUsersRequest{Ids: []string{first.ID.String(), "not-a-uuid"}})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("got %v, want %v", err, codes.InvalidArgument)
+		}
+	})
+}
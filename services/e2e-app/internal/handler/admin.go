@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/tanerincode/e2e-app/internal/model"
+	"github.com/tanerincode/e2e-app/internal/repository"
+	"github.com/tanerincode/e2e-app/internal/service"
+)
+
+// AdminHandler handles user management by administrators
+type AdminHandler struct {
+	userService *service.UserService
+	authService service.AuthService
+}
+
+// NewAdminHandler creates a new AdminHandler
+func NewAdminHandler(userService *service.UserService, authService service.AuthService) *AdminHandler {
+	return &AdminHandler{
+		userService: userService,
+		authService: authService,
+	}
+}
+
+// ListUsers returns a page of users, optionally filtered by email, creation
+// date and status
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	var req model.ListUsersRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.userService.ListUsers(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, page.ToResponse())
+}
+
+// GetUser returns a single user
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userService.GetUserByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, user.ToResponse())
+}
+
+// DisableUser blocks a user from signing in and ends their sessions
+func (h *AdminHandler) DisableUser(c *gin.Context) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userService.DisableUser(c.Request.Context(), actorID, id)
+	if err != nil {
+		respondAdminError(c, "disable user", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user.ToResponse())
+}
+
+// EnableUser lets a disabled user sign in again
+func (h *AdminHandler) EnableUser(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userService.EnableUser(c.Request.Context(), id)
+	if err != nil {
+		respondAdminError(c, "enable user", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user.ToResponse())
+}
+
+// ForcePasswordReset invalidates a user's password and sessions and mails
+// them a reset link
+func (h *AdminHandler) ForcePasswordReset(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.authService.ForcePasswordReset(c.Request.Context(), id); err != nil {
+		respondAdminError(c, "force password reset", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password reset email sent"})
+}
+
+// RevokeSessions signs a user out everywhere
+func (h *AdminHandler) RevokeSessions(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.RevokeSessions(c.Request.Context(), id); err != nil {
+		respondAdminError(c, "revoke sessions", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "all sessions revoked successfully"})
+}
+
+// DeleteUser deletes a user's account
+func (h *AdminHandler) DeleteUser(c *gin.Context) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.RemoveUser(c.Request.Context(), actorID, id); err != nil {
+		respondAdminError(c, "delete user", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "user deleted successfully"})
+}
+
+// userIDParam returns the user ID from the path, writing an error response
+// when it isn't a valid ID
+func userIDParam(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func respondAdminError(c *gin.Context, action string, err error) {
+	switch {
+	case errors.Is(err, service.ErrCannotModifySelf):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Failed to %s: %v", action, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to " + action})
+	}
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanerincode/e2e-app/internal/model"
+	"github.com/tanerincode/e2e-app/internal/service"
+)
+
+const (
+	// federationStateCookie holds the state of a federated login while the
+	// user is away at the provider
+	federationStateCookie = "federation_state"
+	federationCookiePath  = "/api/v1/auth/federated"
+)
+
+// FederationHandler handles sign in through upstream OpenID Connect providers
+type FederationHandler struct {
+	authService service.AuthService
+}
+
+// NewFederationHandler creates a new FederationHandler
+func NewFederationHandler(authService service.AuthService) *FederationHandler {
+	return &FederationHandler{
+		authService: authService,
+	}
+}
+
+// ListProviders returns the providers users can sign in with
+func (h *FederationHandler) ListProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, model.FederatedProvidersResponse{Providers: h.authService.FederatedProviders()})
+}
+
+// Start redirects the user to the provider to sign in
+func (h *FederationHandler) Start(c *gin.Context) {
+	authURL, stateToken, err := h.authService.StartFederatedLogin(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownFederatedProvider) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Failed to start federated login: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "identity provider is unavailable"})
+		return
+	}
+
+	// The callback is a top-level navigation from the provider's site, so
+	// the cookie can't be SameSite=Strict
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(federationStateCookie, stateToken, 0, federationCookiePath, "", isSecureRequest(c), true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback finishes the login when the provider sends the user back. The
+// response is the same as the one of the password login.
+func (h *FederationHandler) Callback(c *gin.Context) {
+	stateToken, _ := c.Cookie(federationStateCookie)
+	// The state is single-use either way
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(federationStateCookie, "", -1, federationCookiePath, "", isSecureRequest(c), true)
+
+	if providerError := c.Query("error"); providerError != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":             "identity provider did not sign the user in",
+			"provider_error":    providerError,
+			"error_description": c.Query("error_description"),
+		})
+		return
+	}
+
+	result, err := h.authService.CompleteFederatedLogin(c.Request.Context(), c.Param("provider"), stateToken, c.Query("state"), c.Query("code"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnknownFederatedProvider):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrInvalidFederationState):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrFederatedEmailNotVerified), errors.Is(err, service.ErrFederatedLinkNotAllowed), errors.Is(err, service.ErrAccountDisabled):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			log.Printf("Failed to complete federated login: %v", err)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "federated login failed"})
+		}
+		return
+	}
+
+	if result.Challenge != nil {
+		c.JSON(http.StatusOK, result.Challenge)
+		return
+	}
+	c.JSON(http.StatusOK, result.Tokens)
+}
+
+// isSecureRequest reports whether the request reached us over HTTPS, directly
+// or through a TLS terminating proxy
+func isSecureRequest(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+}
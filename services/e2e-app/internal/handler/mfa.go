@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/tanerincode/e2e-app/internal/model"
+	"github.com/tanerincode/e2e-app/internal/service"
+)
+
+// MFAHandler manages two-factor authentication for the signed in user
+type MFAHandler struct {
+	authService service.AuthService
+}
+
+// NewMFAHandler creates a new MFAHandler
+func NewMFAHandler(authService service.AuthService) *MFAHandler {
+	return &MFAHandler{
+		authService: authService,
+	}
+}
+
+// EnrollTOTP starts TOTP enrollment and returns the secret and provisioning URI
+func (h *MFAHandler) EnrollTOTP(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	enrollment, err := h.authService.EnrollTOTP(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, service.ErrTOTPAlreadyEnabled) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmTOTP enables TOTP with the first code from the authenticator and
+// returns the recovery codes
+func (h *MFAHandler) ConfirmTOTP(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req model.ConfirmTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.authService.ConfirmTOTP(c.Request.Context(), id, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrTOTPAlreadyEnabled):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrTOTPNotEnrolled), errors.Is(err, service.ErrInvalidMFACode):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, model.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTOTP turns TOTP off after re-checking the password and a second
+// factor, and signs out the user's other sessions
+func (h *MFAHandler) DisableTOTP(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	claims, _ := currentClaims(c)
+	sessionID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session ID"})
+		return
+	}
+
+	var req model.DisableTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.DisableTOTP(c.Request.Context(), id, sessionID, req.Password, req.Code, c.ClientIP()); err != nil {
+		if respondLocked(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, service.ErrInvalidCredentials):
+			c.JSON(http.StatusForbidden, gin.H{"error": "password is incorrect"})
+		case errors.Is(err, service.ErrInvalidMFACode):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrTOTPNotEnrolled):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication disabled"})
+}
+
+// currentUserID returns the ID of the authenticated user, writing an error
+// response when there is none
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
@@ -1,15 +1,31 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/tanerincode/e2e-app/internal/config"
+	"github.com/tanerincode/e2e-app/internal/service"
 )
 
-func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+// AuthMiddleware lets requests through that carry a valid first-party access
+// token. Tokens issued to OAuth clients are only good at /userinfo.
+func AuthMiddleware(authService service.AuthService) gin.HandlerFunc {
+	return authenticate(authService.ValidateAccessToken, false)
+}
+
+// UserInfoMiddleware is AuthMiddleware for /userinfo, which also accepts
+// access tokens issued to OAuth clients
+func UserInfoMiddleware(authService service.AuthService) gin.HandlerFunc {
+	return authenticate(authService.ValidateUserInfoToken, true)
+}
+
+// tokenValidator verifies an access token and returns its claims
+type tokenValidator func(ctx context.Context, accessToken string) (*service.AccessClaims, error)
+
+func authenticate(validate tokenValidator, allowClients bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -27,25 +43,58 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		tokenString := parts[1]
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return []byte(cfg.JWTSecret), nil
-		})
-
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		claims, err := validate(c.Request.Context(), tokenString)
+		if err != nil {
+			if errors.Is(err, service.ErrTokenRevoked) || errors.Is(err, service.ErrAccountDisabled) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			} else if errors.Is(err, service.ErrInvalidToken) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to validate token"})
+			}
+			c.Abort()
+			return
+		}
+		// Client tokens already miss the audience; this holds even if the
+		// audiences are misconfigured
+		if claims.ClientID != "" && !allowClients {
+			c.JSON(http.StatusForbidden, gin.H{"error": "token was issued to an OAuth client"})
 			c.Abort()
 			return
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
+		// Add user ID and token claims to context
+		c.Set("user_id", claims.UserID)
+		c.Set("claims", claims)
+		c.Next()
+	}
+}
+
+// RequirePermission only lets requests through whose access token grants the
+// permission. It must run after AuthMiddleware.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := currentClaims(c)
 		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			c.Abort()
+			return
+		}
+		if !claims.HasPermission(permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
 			c.Abort()
 			return
 		}
-
-		// Add user ID to context
-		c.Set("user_id", claims["user_id"])
 		c.Next()
 	}
+}
+
+// currentClaims returns the access token claims set by AuthMiddleware
+func currentClaims(c *gin.Context) (*service.AccessClaims, bool) {
+	value, exists := c.Get("claims")
+	if !exists {
+		return nil, false
+	}
+	claims, ok := value.(*service.AccessClaims)
+	return claims, ok
 }
\ No newline at end of file
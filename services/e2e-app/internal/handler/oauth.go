@@ -0,0 +1,267 @@
+package handler
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanerincode/e2e-app/internal/model"
+	"github.com/tanerincode/e2e-app/internal/service"
+	"github.com/tanerincode/e2e-app/internal/throttle"
+)
+
+//go:embed templates/authorize.html
+var authorizeTemplateSource string
+
+var authorizeTemplate = template.Must(template.New("authorize").Parse(authorizeTemplateSource))
+
+// authorizePage is the data the sign in page is rendered with
+type authorizePage struct {
+	ClientName string
+	Request    *model.AuthorizationRequest
+	Email      string
+	MFAToken   string
+	Error      string
+}
+
+// OAuthHandler serves the OpenID Connect provider endpoints
+type OAuthHandler struct {
+	authService service.AuthService
+}
+
+// NewOAuthHandler creates a new OAuthHandler
+func NewOAuthHandler(authService service.AuthService) *OAuthHandler {
+	return &OAuthHandler{
+		authService: authService,
+	}
+}
+
+// Authorize validates an authorization request and shows the sign in page
+func (h *OAuthHandler) Authorize(c *gin.Context) {
+	var req model.AuthorizationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.String(http.StatusBadRequest, "Invalid authorization request")
+		return
+	}
+
+	client, err := h.authService.ValidateAuthorizationRequest(c.Request.Context(), &req)
+	if err != nil {
+		authorizationError(c, &req, err)
+		return
+	}
+
+	renderAuthorizePage(c, http.StatusOK, &authorizePage{ClientName: client.Name, Request: &req})
+}
+
+// SubmitAuthorize handles the sign in form and redirects back to the client
+// with an authorization code once the user is signed in
+func (h *OAuthHandler) SubmitAuthorize(c *gin.Context) {
+	var req model.AuthorizationRequest
+	var creds model.AuthorizationCredentials
+	if err := c.ShouldBind(&req); err != nil {
+		c.String(http.StatusBadRequest, "Invalid authorization request")
+		return
+	}
+	if err := c.ShouldBind(&creds); err != nil {
+		c.String(http.StatusBadRequest, "Invalid authorization request")
+		return
+	}
+
+	client, err := h.authService.ValidateAuthorizationRequest(c.Request.Context(), &req)
+	if err != nil {
+		authorizationError(c, &req, err)
+		return
+	}
+
+	code, challenge, err := h.authService.Authorize(c.Request.Context(), &req, &creds, c.ClientIP())
+	page := &authorizePage{ClientName: client.Name, Request: &req, Email: creds.Email}
+
+	var locked *throttle.LockedError
+	switch {
+	case err == nil && challenge != nil:
+		page.MFAToken = challenge.MFAToken
+		renderAuthorizePage(c, http.StatusOK, page)
+	case err == nil:
+		params := url.Values{"code": {code}}
+		if req.State != "" {
+			params.Set("state", req.State)
+		}
+		c.Redirect(http.StatusFound, redirectWithParams(req.RedirectURI, params))
+	case errors.As(err, &locked):
+		page.MFAToken = creds.MFAToken
+		page.Error = fmt.Sprintf("Too many failed sign in attempts. Try again in %s.", locked.RetryAfter.Round(time.Second))
+		setRetryAfter(c, locked.RetryAfter)
+		renderAuthorizePage(c, http.StatusTooManyRequests, page)
+	case errors.Is(err, service.ErrEmailNotVerified):
+		page.Error = "Please verify your email address before signing in."
+		renderAuthorizePage(c, http.StatusForbidden, page)
+	case errors.Is(err, service.ErrAccountDisabled):
+		page.Error = "This account has been disabled."
+		renderAuthorizePage(c, http.StatusForbidden, page)
+	case errors.Is(err, service.ErrInvalidMFACode):
+		page.MFAToken = creds.MFAToken
+		page.Error = "That code is not valid."
+		renderAuthorizePage(c, http.StatusUnauthorized, page)
+	case errors.Is(err, service.ErrInvalidMFAToken):
+		page.Error = "Your sign in attempt expired, please sign in again."
+		renderAuthorizePage(c, http.StatusUnauthorized, page)
+	default:
+		page.Error = "Invalid email or password."
+		renderAuthorizePage(c, http.StatusUnauthorized, page)
+	}
+}
+
+// Token is the OAuth token endpoint. Clients authenticate with HTTP Basic
+// auth or with client_id and client_secret in the form body.
+func (h *OAuthHandler) Token(c *gin.Context) {
+	// Token responses must never be cached (RFC 6749 section 5.1)
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var req model.OAuthTokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": err.Error()})
+		return
+	}
+
+	id, secret, basicAuth := c.Request.BasicAuth()
+	if basicAuth {
+		// Basic credentials are form encoded before being base64 encoded
+		req.ClientID, _ = url.QueryUnescape(id)
+		req.ClientSecret, _ = url.QueryUnescape(secret)
+	}
+
+	tokens, err := h.authService.ExchangeToken(c.Request.Context(), &req)
+	if err != nil {
+		var oauthErr *service.OAuthError
+		if !errors.As(err, &oauthErr) {
+			log.Printf("Failed to exchange OAuth token: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+			return
+		}
+
+		status := http.StatusBadRequest
+		if oauthErr.Code == "invalid_client" {
+			status = http.StatusUnauthorized
+			if basicAuth {
+				c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+			}
+		}
+		c.JSON(status, gin.H{"error": oauthErr.Code, "error_description": oauthErr.Description})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Introspect is the OAuth token introspection endpoint (RFC 7662). Clients
+// authenticate the same way as at the token endpoint.
+func (h *OAuthHandler) Introspect(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var req model.IntrospectionRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": err.Error()})
+		return
+	}
+
+	id, secret, basicAuth := c.Request.BasicAuth()
+	if basicAuth {
+		req.ClientID, _ = url.QueryUnescape(id)
+		req.ClientSecret, _ = url.QueryUnescape(secret)
+	}
+
+	result, err := h.authService.Introspect(c.Request.Context(), &req)
+	if err != nil {
+		var oauthErr *service.OAuthError
+		if !errors.As(err, &oauthErr) {
+			log.Printf("Failed to introspect token: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+			return
+		}
+
+		if basicAuth {
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": oauthErr.Code, "error_description": oauthErr.Description})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// UserInfo returns the OpenID Connect claims about the token's user
+func (h *OAuthHandler) UserInfo(c *gin.Context) {
+	claims, ok := currentClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	info, err := h.authService.UserInfo(c.Request.Context(), claims)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, info)
+}
+
+// authorizationError reports a failed authorization request. Problems with
+// the client or redirect URI are shown to the user, since redirecting to an
+// unverified URI would make this an open redirector; everything else is sent
+// back to the client.
+func authorizationError(c *gin.Context, req *model.AuthorizationRequest, err error) {
+	var oauthErr *service.OAuthError
+	switch {
+	case errors.Is(err, service.ErrInvalidAuthorizationTarget):
+		c.String(http.StatusBadRequest, "The application sent an invalid sign in request: %s.", err)
+	case errors.As(err, &oauthErr):
+		params := url.Values{
+			"error":             {oauthErr.Code},
+			"error_description": {oauthErr.Description},
+		}
+		if req.State != "" {
+			params.Set("state", req.State)
+		}
+		c.Redirect(http.StatusFound, redirectWithParams(req.RedirectURI, params))
+	default:
+		log.Printf("Failed to validate authorization request: %v", err)
+		c.String(http.StatusInternalServerError, "Something went wrong, please try again.")
+	}
+}
+
+// renderAuthorizePage writes the sign in page. It must not be framed by other
+// sites, which would allow clickjacking the sign in.
+func renderAuthorizePage(c *gin.Context, status int, page *authorizePage) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Frame-Options", "DENY")
+	c.Header("Content-Security-Policy", "frame-ancestors 'none'")
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(status)
+	if err := authorizeTemplate.Execute(c.Writer, page); err != nil {
+		log.Printf("Failed to render sign in page: %v", err)
+	}
+}
+
+// redirectWithParams adds query parameters to a redirect URI, keeping the
+// ones it already has
+func redirectWithParams(uri string, params url.Values) string {
+	target, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	query := target.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	target.RawQuery = query.Encode()
+	return target.String()
+}
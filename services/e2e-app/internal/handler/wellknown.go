@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanerincode/e2e-app/internal/service"
+)
+
+type WellKnownHandler struct {
+	keys        *service.KeySet
+	authService service.AuthService
+}
+
+func NewWellKnownHandler(keys *service.KeySet, authService service.AuthService) *WellKnownHandler {
+	return &WellKnownHandler{
+		keys:        keys,
+		authService: authService,
+	}
+}
+
+// JWKS serves the public keys access tokens can be verified with
+func (h *WellKnownHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.keys.JWKS())
+}
+
+// OpenIDConfiguration serves the OpenID Connect discovery document
+func (h *WellKnownHandler) OpenIDConfiguration(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.authService.OpenIDConfiguration())
+}
@@ -0,0 +1,34 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tanerincode/e2e-app/internal/config"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers emails
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewSender returns the sender selected by cfg.MailDriver
+func NewSender(cfg *config.Config) (Sender, error) {
+	switch cfg.MailDriver {
+	case "log", "":
+		return NewLogSender(), nil
+	case "file":
+		return NewFileSender(cfg.MailDir)
+	case "smtp":
+		return NewSMTPSender(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.MailDriver)
+	}
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Login methods
+const (
+	LoginPassword  = "password"
+	LoginMFA       = "mfa"
+	LoginFederated = "federated"
+)
+
+// Outcomes of logins and refreshes
+const (
+	ResultSuccess     = "success"
+	ResultFailure     = "failure"
+	ResultMFARequired = "mfa_required"
+	ResultReused      = "reused"
+)
+
+// Token flows
+const (
+	FlowFirstParty = "first_party"
+	FlowOAuth      = "oauth"
+)
+
+var (
+	logins = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_logins_total",
+		Help: "Login attempts by method and result.",
+	}, []string{"method", "result"})
+
+	tokensIssued = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_tokens_issued_total",
+		Help: "Access and refresh token pairs issued, by flow.",
+	}, []string{"flow"})
+
+	tokenRefreshes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_token_refreshes_total",
+		Help: "Refresh token exchanges by result. Reused means a rotated token was presented again and its session was revoked.",
+	}, []string{"result"})
+)
+
+// RecordLogin counts a login attempt. A login that passed this step but still
+// needs a second factor counts as mfa_required.
+func RecordLogin(method string, mfaRequired bool, err error) {
+	result := ResultSuccess
+	switch {
+	case err != nil:
+		result = ResultFailure
+	case mfaRequired:
+		result = ResultMFARequired
+	}
+	logins.WithLabelValues(method, result).Inc()
+}
+
+// RecordTokensIssued counts a token pair issued in flow
+func RecordTokensIssued(flow string) {
+	tokensIssued.WithLabelValues(flow).Inc()
+}
+
+// RecordRefresh counts a refresh token exchange with the given result
+func RecordRefresh(result string) {
+	tokenRefreshes.WithLabelValues(result).Inc()
+}
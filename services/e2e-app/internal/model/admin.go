@@ -0,0 +1,56 @@
+package model
+
+import "time"
+
+// UserFilter narrows down a user listing. Email matches any part of the
+// address, ignoring case. A Limit of zero returns every match.
+type UserFilter struct {
+	Email         string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Disabled      *bool
+	Offset        int
+	Limit         int
+}
+
+// ListUsersRequest holds the query parameters of the admin user listing.
+// Dates are RFC 3339 timestamps.
+type ListUsersRequest struct {
+	Email         string     `form:"email"`
+	CreatedAfter  *time.Time `form:"created_after"`
+	CreatedBefore *time.Time `form:"created_before"`
+	Disabled      *bool      `form:"disabled"`
+	Page          int        `form:"page" binding:"omitempty,min=1"`
+	PageSize      int        `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+// UserPage is a page of a user listing along with the number of matching
+// users in total
+type UserPage struct {
+	Users    []*User
+	Total    int64
+	Page     int
+	PageSize int
+}
+
+// ToResponse converts UserPage to UserListResponse
+func (p *UserPage) ToResponse() UserListResponse {
+	users := make([]UserResponse, 0, len(p.Users))
+	for _, user := range p.Users {
+		users = append(users, user.ToResponse())
+	}
+	return UserListResponse{
+		Users:    users,
+		Total:    p.Total,
+		Page:     p.Page,
+		PageSize: p.PageSize,
+	}
+}
+
+// UserListResponse is a page of users
+type UserListResponse struct {
+	Users    []UserResponse `json:"users"`
+	Total    int64          `json:"total"`
+	Page     int            `json:"page"`
+	PageSize int            `json:"page_size"`
+}
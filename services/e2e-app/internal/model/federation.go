@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LinkedIdentity ties an account at an upstream OpenID Connect provider to a
+// user. The provider's subject identifies the account, since the email it
+// reports may change.
+type LinkedIdentity struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	UserID      uuid.UUID  `gorm:"type:uuid;index;not null" json:"user_id"`
+	Provider    string     `gorm:"size:64;not null;uniqueIndex:idx_linked_identities_provider_subject" json:"provider"`
+	Subject     string     `gorm:"size:255;not null;uniqueIndex:idx_linked_identities_provider_subject" json:"subject"`
+	Email       string     `gorm:"size:255" json:"email"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID.
+func (i *LinkedIdentity) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName specifies the table name for the LinkedIdentity model
+func (LinkedIdentity) TableName() string {
+	return "linked_identities"
+}
+
+// FederatedProvidersResponse lists the upstream providers users can sign in with
+type FederatedProvidersResponse struct {
+	Providers []string `json:"providers"`
+}
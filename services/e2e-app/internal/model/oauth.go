@@ -0,0 +1,174 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OAuthClient is an application registered to sign users in through the
+// OpenID Connect endpoints. Public clients, such as single page and native
+// apps, have no secret and rely on PKCE alone.
+type OAuthClient struct {
+	ID           string    `gorm:"size:64;primary_key" json:"client_id"`
+	SecretHash   string    `gorm:"size:255" json:"-"`
+	Name         string    `gorm:"size:100;not null" json:"name"`
+	RedirectURIs string    `gorm:"type:text;not null" json:"-"`
+	Public       bool      `gorm:"not null;default:false" json:"public"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for the OAuthClient model
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+// GetRedirectURIs returns the registered redirect URIs, which are stored one per line
+func (c *OAuthClient) GetRedirectURIs() []string {
+	var uris []string
+	for _, uri := range strings.Split(c.RedirectURIs, "\n") {
+		if uri = strings.TrimSpace(uri); uri != "" {
+			uris = append(uris, uri)
+		}
+	}
+	return uris
+}
+
+// AllowsRedirectURI reports whether uri exactly matches a registered redirect URI
+func (c *OAuthClient) AllowsRedirectURI(uri string) bool {
+	for _, registered := range c.GetRedirectURIs() {
+		if registered == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthorizationCode is a single-use OAuth authorization code. Only a SHA-256
+// hash of the code is stored. FamilyID is the session the code turns into,
+// so the session can be revoked if the code is ever presented twice.
+type AuthorizationCode struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	CodeHash      string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	ClientID      string     `gorm:"size:64;index;not null" json:"client_id"`
+	UserID        uuid.UUID  `gorm:"type:uuid;index;not null" json:"user_id"`
+	FamilyID      uuid.UUID  `gorm:"type:uuid;not null" json:"family_id"`
+	RedirectURI   string     `gorm:"type:text;not null" json:"redirect_uri"`
+	Scope         string     `gorm:"size:255" json:"scope"`
+	Nonce         string     `gorm:"size:255" json:"-"`
+	CodeChallenge string     `gorm:"size:128;not null" json:"-"`
+	AMR           string     `gorm:"size:64" json:"amr"`
+	AuthTime      time.Time  `gorm:"not null" json:"auth_time"`
+	ExpiresAt     time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt        *time.Time `json:"used_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID.
+func (c *AuthorizationCode) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName specifies the table name for the AuthorizationCode model
+func (AuthorizationCode) TableName() string {
+	return "authorization_codes"
+}
+
+// AuthorizationRequest holds the parameters of an /oauth/authorize request
+type AuthorizationRequest struct {
+	ResponseType        string `form:"response_type"`
+	ClientID            string `form:"client_id"`
+	RedirectURI         string `form:"redirect_uri"`
+	Scope               string `form:"scope"`
+	State               string `form:"state"`
+	Nonce               string `form:"nonce"`
+	CodeChallenge       string `form:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method"`
+}
+
+// AuthorizationCredentials are what the user enters on the sign in page.
+// The second step of a two-factor login sends MFAToken and Code instead of
+// the password.
+type AuthorizationCredentials struct {
+	Email    string `form:"email"`
+	Password string `form:"password"`
+	MFAToken string `form:"mfa_token"`
+	Code     string `form:"code"`
+}
+
+// OAuthTokenRequest holds the parameters of an /oauth/token request
+type OAuthTokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// IntrospectionRequest holds the parameters of an /oauth/introspect request
+// (RFC 7662). token_type_hint is "access_token" or "refresh_token".
+type IntrospectionRequest struct {
+	Token         string `form:"token" binding:"required"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientID      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
+
+// TokenIntrospection describes a token (RFC 7662 section 2.2). Only Active is
+// set for tokens that are expired, revoked, unknown, belong to a disabled
+// account or weren't issued to the introspecting client. TokenType is
+// "access_token" or "refresh_token".
+type TokenIntrospection struct {
+	Active      bool     `json:"active"`
+	TokenType   string   `json:"token_type,omitempty"`
+	Subject     string   `json:"sub,omitempty"`
+	Username    string   `json:"username,omitempty"`
+	Issuer      string   `json:"iss,omitempty"`
+	Audience    []string `json:"aud,omitempty"`
+	IssuedAt    int64    `json:"iat,omitempty"`
+	ExpiresAt   int64    `json:"exp,omitempty"`
+	TokenID     string   `json:"jti,omitempty"`
+	SessionID   string   `json:"sid,omitempty"`
+	ClientID    string   `json:"client_id,omitempty"`
+	Scope       string   `json:"scope,omitempty"`
+	AMR         []string `json:"amr,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// UserInfo is the OpenID Connect userinfo response. Claims beyond sub are
+// only included for the scopes the token was granted.
+type UserInfo struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+	Name          string `json:"name,omitempty"`
+	GivenName     string `json:"given_name,omitempty"`
+	FamilyName    string `json:"family_name,omitempty"`
+}
+
+// OpenIDConfiguration is the discovery document served on
+// /.well-known/openid-configuration
+type OpenIDConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RefreshToken is a persisted refresh token. Tokens issued from the same login
+// share a FamilyID; every refresh rotates the presented token and issues the
+// next one in the family.
+type RefreshToken struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	FamilyID  uuid.UUID  `gorm:"type:uuid;index;not null" json:"family_id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;index;not null" json:"user_id"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	RotatedAt *time.Time `json:"rotated_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID.
+func (t *RefreshToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName specifies the table name for the RefreshToken model
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// RevokedToken is an entry in the access token denylist. Entries only need to
+// be kept until the token they refer to would have expired anyway.
+type RevokedToken struct {
+	JTI       uuid.UUID `gorm:"type:uuid;primary_key" json:"jti"`
+	UserID    uuid.UUID `gorm:"type:uuid;index;not null" json:"user_id"`
+	ExpiresAt time.Time `gorm:"index;not null" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for the RevokedToken model
+func (RevokedToken) TableName() string {
+	return "revoked_tokens"
+}
+
+// RevokedUser records that every session of a user was ended. Unlike the
+// user's refresh tokens it has no foreign key, so it outlives a deleted
+// account and services with a copy of the revocation list still refuse its
+// access tokens.
+type RevokedUser struct {
+	UserID    uuid.UUID `gorm:"type:uuid;primary_key" json:"user_id"`
+	RevokedAt time.Time `gorm:"index;not null" json:"revoked_at"`
+}
+
+// TableName specifies the table name for the RevokedUser model
+func (RevokedUser) TableName() string {
+	return "revoked_users"
+}
+
+// Revocations lists what was revoked among access tokens that haven't
+// expired yet
+type Revocations struct {
+	TokenIDs    []uuid.UUID
+	SessionIDs  []uuid.UUID
+	Users       []*RevokedUser
+	GeneratedAt time.Time
+}
+
+// PasswordResetToken is a single-use password reset token. Only a SHA-256
+// hash of the token is stored; the token itself is only ever sent by email.
+type PasswordResetToken struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;index;not null" json:"user_id"`
+	TokenHash string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID.
+func (t *PasswordResetToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName specifies the table name for the PasswordResetToken model
+func (PasswordResetToken) TableName() string {
+	return "password_reset_tokens"
+}
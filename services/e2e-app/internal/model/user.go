@@ -1,6 +1,7 @@
 package model
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,13 +9,22 @@ import (
 )
 
 type User struct {
-	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
-	Email     string    `gorm:"uniqueIndex;not null" json:"email"`
-	Password  string    `gorm:"not null" json:"-"`
-	FirstName string    `gorm:"size:100" json:"first_name"`
-	LastName  string    `gorm:"size:100" json:"last_name"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	Email           string     `gorm:"uniqueIndex;not null" json:"email"`
+	Password        string     `gorm:"not null" json:"-"`
+	FirstName       string     `gorm:"size:100" json:"first_name"`
+	LastName        string     `gorm:"size:100" json:"last_name"`
+	EmailVerified   bool       `gorm:"not null;default:false" json:"email_verified"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	TOTPEnabled     bool       `gorm:"column:totp_enabled;not null;default:false" json:"totp_enabled"`
+	TOTPSecret      string     `gorm:"column:totp_secret;size:64" json:"-"`
+	TOTPLastStep    int64      `gorm:"column:totp_last_step;not null;default:0" json:"-"`
+	Roles           string     `gorm:"type:text;not null;default:''" json:"-"`
+	Permissions     string     `gorm:"type:text;not null;default:''" json:"-"`
+	Disabled        bool       `gorm:"not null;default:false" json:"disabled"`
+	DisabledAt      *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
 // BeforeCreate will set a UUID rather than numeric ID.
@@ -30,23 +40,87 @@ func (User) TableName() string {
 	return "users"
 }
 
+// GetRoles returns the roles granted to the user, which are stored space separated
+func (u *User) GetRoles() []string {
+	return strings.Fields(u.Roles)
+}
+
+// GetPermissions returns the permissions granted to the user directly, on
+// top of the ones their roles grant
+func (u *User) GetPermissions() []string {
+	return strings.Fields(u.Permissions)
+}
+
+// GrantRole adds a role, reporting whether the user didn't have it yet
+func (u *User) GrantRole(role string) bool {
+	return addToList(&u.Roles, role)
+}
+
+// RevokeRole removes a role, reporting whether the user had it
+func (u *User) RevokeRole(role string) bool {
+	return removeFromList(&u.Roles, role)
+}
+
+// GrantPermission adds a directly granted permission, reporting whether the
+// user didn't have it yet
+func (u *User) GrantPermission(permission string) bool {
+	return addToList(&u.Permissions, permission)
+}
+
+// RevokePermission removes a directly granted permission, reporting whether
+// the user had it
+func (u *User) RevokePermission(permission string) bool {
+	return removeFromList(&u.Permissions, permission)
+}
+
+func addToList(list *string, value string) bool {
+	values := strings.Fields(*list)
+	for _, existing := range values {
+		if existing == value {
+			return false
+		}
+	}
+	*list = strings.Join(append(values, value), " ")
+	return true
+}
+
+func removeFromList(list *string, value string) bool {
+	values := strings.Fields(*list)
+	kept := make([]string, 0, len(values))
+	for _, existing := range values {
+		if existing != value {
+			kept = append(kept, existing)
+		}
+	}
+	*list = strings.Join(kept, " ")
+	return len(kept) != len(values)
+}
+
 // UserResponse is used for sending user data in API responses
 type UserResponse struct {
-	ID        uuid.UUID `json:"id"`
-	Email     string    `json:"email"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	CreatedAt time.Time `json:"created_at"`
+	ID            uuid.UUID `json:"id"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	TOTPEnabled   bool      `json:"totp_enabled"`
+	Roles         []string  `json:"roles"`
+	Disabled      bool      `json:"disabled"`
+	FirstName     string    `json:"first_name"`
+	LastName      string    `json:"last_name"`
+	CreatedAt     time.Time `json:"created_at"`
 }
 
 // ToResponse converts User to UserResponse
 func (u *User) ToResponse() UserResponse {
 	return UserResponse{
-		ID:        u.ID,
-		Email:     u.Email,
-		FirstName: u.FirstName,
-		LastName:  u.LastName,
-		CreatedAt: u.CreatedAt,
+		ID:            u.ID,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+		TOTPEnabled:   u.TOTPEnabled,
+		Roles:         u.GetRoles(),
+		Disabled:      u.Disabled,
+		FirstName:     u.FirstName,
+		LastName:      u.LastName,
+		CreatedAt:     u.CreatedAt,
 	}
 }
 
@@ -64,10 +138,50 @@ type RegisterRequest struct {
 	LastName  string `json:"last_name" binding:"required"`
 }
 
+// VerifyEmailRequest represents the email verification request body
+type VerifyEmailRequest struct {
+	Token string `json:"token" form:"token" binding:"required"`
+}
+
+// ResendVerificationRequest represents the request body for resending the verification email
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ForgotPasswordRequest represents the request body for starting a password reset
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest represents the request body for completing a password reset
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// UpdateProfileRequest represents the request body for updating the account's profile fields
+type UpdateProfileRequest struct {
+	FirstName *string `json:"first_name" binding:"omitempty,min=1,max=100"`
+	LastName  *string `json:"last_name" binding:"omitempty,min=1,max=100"`
+}
+
+// ChangePasswordRequest represents the request body for changing the password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+// DeleteAccountRequest represents the request body for deleting the account
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
 // TokenResponse represents the authentication token response
 type TokenResponse struct {
 	AccessToken  string `json:"access_token"`
 	TokenType    string `json:"token_type"`
 	ExpiresIn    int64  `json:"expires_in"`
 	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
 }
\ No newline at end of file
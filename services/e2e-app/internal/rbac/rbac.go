@@ -0,0 +1,75 @@
+package rbac
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Roles that can be granted to users. Users without a role are regular
+// users and can only act on their own account.
+const (
+	RoleAdmin = "admin"
+)
+
+// Permissions checked by the services. They are granted through roles or
+// directly to a user.
+const (
+	// PermissionUsersRead allows looking up any user account
+	PermissionUsersRead = "users:read"
+	// PermissionUsersManage allows disabling accounts, revoking their sessions
+	// and forcing password resets
+	PermissionUsersManage = "users:manage"
+	// PermissionProfilesManage allows changing and deleting any profile in
+	// e2e-profile
+	PermissionProfilesManage = "profiles:manage"
+)
+
+// rolePermissions lists the permissions each role grants
+var rolePermissions = map[string][]string{
+	RoleAdmin: {PermissionUsersRead, PermissionUsersManage, PermissionProfilesManage},
+}
+
+// knownPermissions is every permission that can be granted
+var knownPermissions = map[string]bool{
+	PermissionUsersRead:      true,
+	PermissionUsersManage:    true,
+	PermissionProfilesManage: true,
+}
+
+// ValidateRole returns an error for a role that doesn't exist
+func ValidateRole(role string) error {
+	if _, ok := rolePermissions[role]; !ok {
+		return fmt.Errorf("unknown role %q", role)
+	}
+	return nil
+}
+
+// ValidatePermission returns an error for a permission that doesn't exist
+func ValidatePermission(permission string) error {
+	if !knownPermissions[permission] {
+		return fmt.Errorf("unknown permission %q", permission)
+	}
+	return nil
+}
+
+// Permissions returns the effective permissions of a user with the given
+// roles and directly granted permissions, sorted and without duplicates.
+// Unknown roles grant nothing.
+func Permissions(roles, granted []string) []string {
+	set := make(map[string]bool)
+	for _, role := range roles {
+		for _, permission := range rolePermissions[role] {
+			set[permission] = true
+		}
+	}
+	for _, permission := range granted {
+		set[permission] = true
+	}
+
+	permissions := make([]string, 0, len(set))
+	for permission := range set {
+		permissions = append(permissions, permission)
+	}
+	sort.Strings(permissions)
+	return permissions
+}
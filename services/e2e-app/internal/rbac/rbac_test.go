@@ -0,0 +1,43 @@
+package rbac
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPermissions(t *testing.T) {
+	tests := []struct {
+		name    string
+		roles   []string
+		granted []string
+		want    []string
+	}{
+		{name: "regular user", want: []string{}},
+		{name: "admin", roles: []string{RoleAdmin}, want: []string{PermissionProfilesManage, PermissionUsersManage, PermissionUsersRead}},
+		{name: "direct grant", granted: []string{PermissionUsersRead}, want: []string{PermissionUsersRead}},
+		{name: "direct grant also granted by a role", roles: []string{RoleAdmin}, granted: []string{PermissionUsersRead}, want: []string{PermissionProfilesManage, PermissionUsersManage, PermissionUsersRead}},
+		{name: "unknown role", roles: []string{"superuser"}, granted: []string{PermissionProfilesManage}, want: []string{PermissionProfilesManage}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Permissions(tt.roles, tt.granted); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Permissions(%v, %v) = %v, want %v", tt.roles, tt.granted, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := ValidateRole(RoleAdmin); err != nil {
+		t.Errorf("ValidateRole(%q) = %v, want nil", RoleAdmin, err)
+	}
+	if err := ValidateRole("superuser"); err == nil {
+		t.Errorf("ValidateRole accepted an unknown role")
+	}
+	if err := ValidatePermission(PermissionUsersManage); err != nil {
+		t.Errorf("ValidatePermission(%q) = %v, want nil", PermissionUsersManage, err)
+	}
+	if err := ValidatePermission("users:delete"); err == nil {
+		t.Errorf("ValidatePermission accepted an unknown permission")
+	}
+}
@@ -1,11 +1,14 @@
 package repository
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
 
+	"github.com/glebarez/sqlite"
 	"github.com/tanerincode/e2e-app/internal/config"
+	"github.com/tanerincode/e2e-app/internal/model"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -58,15 +61,59 @@ func NewDB(cfg *config.Config) (*gorm.DB, error) {
 	return db, nil
 }
 
-// setupMockDB creates an in-memory mock database for development
+// PingDB checks that the database can be reached
+func PingDB(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database handle: %w", err)
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// CloseDB closes the connection pool behind db
+func CloseDB(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database handle: %w", err)
+	}
+	return sqlDB.Close()
+}
+
+// setupMockDB creates an in-memory SQLite database for development. The data
+// only lives as long as the process does.
 func setupMockDB() (*gorm.DB, error) {
-	// For a real implementation, you would use an SQLite in-memory database or a mock implementation
-	// For now, we'll just return a stub with a message
-	log.Println("Mock database not fully implemented. This is a placeholder!")
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Info),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mock database: %w", err)
+	}
 
-	// Placeholder for the actual implementation
-	// In a real implementation, you'd return something like:
-	// return gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	// SQLite allows a single writer; funnel everything through one connection
+	// so concurrent requests don't fail with "database is locked". Keeping
+	// that connection open also keeps the in-memory database alive.
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mock database handle: %w", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	sqlDB.SetConnMaxLifetime(0)
 
-	return nil, fmt.Errorf("mock database implementation pending")
+	// Create the schema
+	if err := db.AutoMigrate(
+		&model.User{},
+		&model.RefreshToken{},
+		&model.RevokedToken{},
+		&model.RevokedUser{},
+		&model.PasswordResetToken{},
+		&model.LoginAttempt{},
+		&model.RecoveryCode{},
+		&model.OAuthClient{},
+		&model.AuthorizationCode{},
+		&model.LinkedIdentity{},
+	); err != nil {
+		return nil, fmt.Errorf("failed to migrate mock database: %w", err)
+	}
+
+	return db, nil
 }
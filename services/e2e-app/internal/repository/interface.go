@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/tanerincode/e2e-app/internal/model"
 	"github.com/google/uuid"
@@ -11,6 +12,70 @@ type UserRepository interface {
 	Create(ctx context.Context, user *model.User) error
 	GetByID(ctx context.Context, id uuid.UUID) (*model.User, error)
 	GetByEmail(ctx context.Context, email string) (*model.User, error)
-	Update(ctx context.Context, user *model.User) error
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*model.User, error)
+	UpdateName(ctx context.Context, id uuid.UUID, firstName, lastName string) error
+	UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error
+	MarkEmailVerified(ctx context.Context, id uuid.UUID, verifiedAt time.Time) error
+	SetDisabled(ctx context.Context, id uuid.UUID, disabled bool, disabledAt *time.Time) error
+	SetTOTPSecret(ctx context.Context, id uuid.UUID, secret string) error
+	EnableTOTP(ctx context.Context, id uuid.UUID, step int64) error
+	DisableTOTP(ctx context.Context, id uuid.UUID) error
+	UpdateAccess(ctx context.Context, id uuid.UUID, roles, permissions string) error
 	Delete(ctx context.Context, id uuid.UUID) error
+	AdvanceTOTPStep(ctx context.Context, id uuid.UUID, step int64) (bool, error)
+	List(ctx context.Context, filter model.UserFilter) ([]*model.User, int64, error)
+	IsActive(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *model.RefreshToken) error
+	GetByID(ctx context.Context, id uuid.UUID) (*model.RefreshToken, error)
+	MarkRotated(ctx context.Context, id uuid.UUID) (bool, error)
+	RevokeFamily(ctx context.Context, familyID uuid.UUID) error
+	RevokeByUserID(ctx context.Context, userID uuid.UUID) error
+	RevokeOtherFamilies(ctx context.Context, userID, keepFamilyID uuid.UUID) error
+	IsFamilyRevoked(ctx context.Context, familyID uuid.UUID) (bool, error)
+	ListRevokedFamilies(ctx context.Context, since time.Time) ([]uuid.UUID, error)
+	ListRevokedUsers(ctx context.Context, since time.Time) ([]*model.RevokedUser, error)
+	DeleteRevokedUsers(ctx context.Context, before time.Time) error
+}
+
+type RevokedTokenRepository interface {
+	Create(ctx context.Context, token *model.RevokedToken) error
+	IsRevoked(ctx context.Context, jti uuid.UUID) (bool, error)
+	Consume(ctx context.Context, token *model.RevokedToken) (bool, error)
+	ListActive(ctx context.Context) ([]uuid.UUID, error)
+	DeleteExpired(ctx context.Context) error
+}
+
+type PasswordResetTokenRepository interface {
+	Create(ctx context.Context, token *model.PasswordResetToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*model.PasswordResetToken, error)
+	MarkUsed(ctx context.Context, id uuid.UUID) (bool, error)
+	InvalidateByUserID(ctx context.Context, userID uuid.UUID) error
+}
+
+type RecoveryCodeRepository interface {
+	ReplaceForUser(ctx context.Context, userID uuid.UUID, codes []*model.RecoveryCode) error
+	Consume(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error)
+	DeleteByUserID(ctx context.Context, userID uuid.UUID) error
+}
+
+type OAuthClientRepository interface {
+	Create(ctx context.Context, client *model.OAuthClient) error
+	GetByID(ctx context.Context, id string) (*model.OAuthClient, error)
+	List(ctx context.Context) ([]*model.OAuthClient, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type AuthorizationCodeRepository interface {
+	Create(ctx context.Context, code *model.AuthorizationCode) error
+	GetByHash(ctx context.Context, codeHash string) (*model.AuthorizationCode, error)
+	MarkUsed(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+type LinkedIdentityRepository interface {
+	Create(ctx context.Context, identity *model.LinkedIdentity) error
+	GetBySubject(ctx context.Context, provider, subject string) (*model.LinkedIdentity, error)
+	RecordLogin(ctx context.Context, id uuid.UUID, email string) error
 }
\ No newline at end of file
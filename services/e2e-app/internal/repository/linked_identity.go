@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tanerincode/e2e-app/internal/model"
+	"gorm.io/gorm"
+)
+
+type linkedIdentityRepository struct {
+	db *gorm.DB
+}
+
+// NewLinkedIdentityRepository creates a new instance of LinkedIdentityRepository
+func NewLinkedIdentityRepository(db *gorm.DB) LinkedIdentityRepository {
+	return &linkedIdentityRepository{
+		db: db,
+	}
+}
+
+// Create links an upstream identity to a user
+func (r *linkedIdentityRepository) Create(ctx context.Context, identity *model.LinkedIdentity) error {
+	return r.db.WithContext(ctx).Create(identity).Error
+}
+
+// GetBySubject retrieves the identity a provider knows by the given subject
+func (r *linkedIdentityRepository) GetBySubject(ctx context.Context, provider, subject string) (*model.LinkedIdentity, error) {
+	var identity model.LinkedIdentity
+	if err := r.db.WithContext(ctx).First(&identity, "provider = ? AND subject = ?", provider, subject).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("linked identity not found")
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// RecordLogin stores the time of a login through the identity and the email
+// the provider reported for it
+func (r *linkedIdentityRepository) RecordLogin(ctx context.Context, id uuid.UUID, email string) error {
+	return r.db.WithContext(ctx).
+		Model(&model.LinkedIdentity{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"email":         email,
+			"last_login_at": time.Now(),
+		}).Error
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tanerincode/e2e-app/internal/model"
+	"gorm.io/gorm"
+)
+
+type oauthClientRepository struct {
+	db *gorm.DB
+}
+
+// NewOAuthClientRepository creates a new instance of OAuthClientRepository
+func NewOAuthClientRepository(db *gorm.DB) OAuthClientRepository {
+	return &oauthClientRepository{
+		db: db,
+	}
+}
+
+// Create registers a new OAuth client
+func (r *oauthClientRepository) Create(ctx context.Context, client *model.OAuthClient) error {
+	return r.db.WithContext(ctx).Create(client).Error
+}
+
+// GetByID retrieves an OAuth client by its client ID
+func (r *oauthClientRepository) GetByID(ctx context.Context, id string) (*model.OAuthClient, error) {
+	var client model.OAuthClient
+	if err := r.db.WithContext(ctx).First(&client, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("oauth client not found")
+		}
+		return nil, err
+	}
+	return &client, nil
+}
+
+// List returns every registered OAuth client
+func (r *oauthClientRepository) List(ctx context.Context) ([]*model.OAuthClient, error) {
+	var clients []*model.OAuthClient
+	if err := r.db.WithContext(ctx).Order("created_at").Find(&clients).Error; err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
+
+// Delete removes an OAuth client
+func (r *oauthClientRepository) Delete(ctx context.Context, id string) error {
+	result := r.db.WithContext(ctx).Delete(&model.OAuthClient{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("oauth client not found")
+	}
+	return nil
+}
+
+type authorizationCodeRepository struct {
+	db *gorm.DB
+}
+
+// NewAuthorizationCodeRepository creates a new instance of AuthorizationCodeRepository
+func NewAuthorizationCodeRepository(db *gorm.DB) AuthorizationCodeRepository {
+	return &authorizationCodeRepository{
+		db: db,
+	}
+}
+
+// Create stores a new authorization code
+func (r *authorizationCodeRepository) Create(ctx context.Context, code *model.AuthorizationCode) error {
+	return r.db.WithContext(ctx).Create(code).Error
+}
+
+// GetByHash retrieves an authorization code by the hash of its value
+func (r *authorizationCodeRepository) GetByHash(ctx context.Context, codeHash string) (*model.AuthorizationCode, error) {
+	var code model.AuthorizationCode
+	if err := r.db.WithContext(ctx).First(&code, "code_hash = ?", codeHash).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("authorization code not found")
+		}
+		return nil, err
+	}
+	return &code, nil
+}
+
+// MarkUsed marks a code as exchanged. It reports false when the code had
+// already been exchanged before.
+func (r *authorizationCodeRepository) MarkUsed(ctx context.Context, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&model.AuthorizationCode{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
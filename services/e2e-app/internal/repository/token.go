@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tanerincode/e2e-app/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type refreshTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewRefreshTokenRepository creates a new instance of RefreshTokenRepository
+func NewRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{
+		db: db,
+	}
+}
+
+// Create stores a newly issued refresh token
+func (r *refreshTokenRepository) Create(ctx context.Context, token *model.RefreshToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+// GetByID retrieves a refresh token by its ID
+func (r *refreshTokenRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.RefreshToken, error) {
+	var token model.RefreshToken
+	if err := r.db.WithContext(ctx).First(&token, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("refresh token not found")
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkRotated marks a refresh token as used. It reports false when the token
+// had already been rotated or revoked, so concurrent refreshes with the same
+// token can't both succeed.
+func (r *refreshTokenRepository) MarkRotated(ctx context.Context, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&model.RefreshToken{}).
+		Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", id).
+		Update("rotated_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RevokeFamily revokes every refresh token in a token family
+func (r *refreshTokenRepository) RevokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Model(&model.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeByUserID revokes every refresh token family of a user and records
+// the revocation of the user, which stays in place if the user is deleted
+func (r *refreshTokenRepository) RevokeByUserID(ctx context.Context, userID uuid.UUID) error {
+	now := time.Now()
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", now).Error
+		if err != nil {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"revoked_at"}),
+		}).Create(&model.RevokedUser{UserID: userID, RevokedAt: now}).Error
+	})
+}
+
+// RevokeOtherFamilies revokes every refresh token family of a user except one
+func (r *refreshTokenRepository) RevokeOtherFamilies(ctx context.Context, userID, keepFamilyID uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Model(&model.RefreshToken{}).
+		Where("user_id = ? AND family_id <> ? AND revoked_at IS NULL", userID, keepFamilyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// IsFamilyRevoked reports whether a token family has been revoked. A family
+// without any tokens left, for example because the user was deleted, counts
+// as revoked too.
+func (r *refreshTokenRepository) IsFamilyRevoked(ctx context.Context, familyID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&model.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count == 0, nil
+}
+
+// ListRevokedFamilies returns the token families revoked since a point in time
+func (r *refreshTokenRepository) ListRevokedFamilies(ctx context.Context, since time.Time) ([]uuid.UUID, error) {
+	var familyIDs []uuid.UUID
+	err := r.db.WithContext(ctx).
+		Model(&model.RefreshToken{}).
+		Where("revoked_at >= ?", since).
+		Distinct().
+		Pluck("family_id", &familyIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	return familyIDs, nil
+}
+
+// ListRevokedUsers returns the users whose sessions were all revoked since a
+// point in time
+func (r *refreshTokenRepository) ListRevokedUsers(ctx context.Context, since time.Time) ([]*model.RevokedUser, error) {
+	var users []*model.RevokedUser
+	if err := r.db.WithContext(ctx).Where("revoked_at >= ?", since).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// DeleteRevokedUsers removes user revocations made before a point in time
+func (r *refreshTokenRepository) DeleteRevokedUsers(ctx context.Context, before time.Time) error {
+	return r.db.WithContext(ctx).
+		Where("revoked_at < ?", before).
+		Delete(&model.RevokedUser{}).Error
+}
+
+type revokedTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewRevokedTokenRepository creates a new instance of RevokedTokenRepository
+func NewRevokedTokenRepository(db *gorm.DB) RevokedTokenRepository {
+	return &revokedTokenRepository{
+		db: db,
+	}
+}
+
+// Create adds a token to the denylist. Revoking the same token twice is a no-op.
+func (r *revokedTokenRepository) Create(ctx context.Context, token *model.RevokedToken) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(token).Error
+}
+
+// IsRevoked reports whether a token ID is on the denylist
+func (r *revokedTokenRepository) IsRevoked(ctx context.Context, jti uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&model.RevokedToken{}).
+		Where("jti = ?", jti).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Consume adds a single-use token to the denylist and reports whether it was
+// not on it yet. Of concurrent callers presenting the same token only one
+// gets true.
+func (r *revokedTokenRepository) Consume(ctx context.Context, token *model.RevokedToken) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(token)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ListActive returns the denylist entries for tokens that haven't expired
+func (r *revokedTokenRepository) ListActive(ctx context.Context) ([]uuid.UUID, error) {
+	var jtis []uuid.UUID
+	err := r.db.WithContext(ctx).
+		Model(&model.RevokedToken{}).
+		Where("expires_at >= ?", time.Now()).
+		Pluck("jti", &jtis).Error
+	if err != nil {
+		return nil, err
+	}
+	return jtis, nil
+}
+
+// DeleteExpired removes denylist entries for tokens that have expired
+func (r *revokedTokenRepository) DeleteExpired(ctx context.Context) error {
+	return r.db.WithContext(ctx).
+		Where("expires_at < ?", time.Now()).
+		Delete(&model.RevokedToken{}).Error
+}
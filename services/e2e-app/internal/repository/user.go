@@ -3,12 +3,17 @@ package repository
 import (
 	"context"
 	"errors"
+	"strings"
+	"time"
 
 	"github.com/tanerincode/e2e-app/internal/model"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// ErrUserNotFound is returned when no user matches the lookup
+var ErrUserNotFound = errors.New("user not found")
+
 type userRepository struct {
 	db *gorm.DB
 }
@@ -30,7 +35,7 @@ func (r *userRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.User
 	var user model.User
 	if err := r.db.WithContext(ctx).First(&user, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -42,21 +47,97 @@ func (r *userRepository) GetByEmail(ctx context.Context, email string) (*model.U
 	var user model.User
 	if err := r.db.WithContext(ctx).First(&user, "email = ?", email).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
 	return &user, nil
 }
 
-// Update updates user information
-func (r *userRepository) Update(ctx context.Context, user *model.User) error {
-	result := r.db.WithContext(ctx).Save(user)
+// GetByIDs retrieves the users with the given IDs. IDs without a user are
+// skipped, so fewer users than IDs may come back, in no particular order.
+func (r *userRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*model.User, error) {
+	var users []*model.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// UpdateName sets the first and last name of a user
+func (r *userRepository) UpdateName(ctx context.Context, id uuid.UUID, firstName, lastName string) error {
+	return r.updateColumns(ctx, id, map[string]interface{}{
+		"first_name": firstName,
+		"last_name":  lastName,
+	})
+}
+
+// UpdatePassword replaces the password hash of a user
+func (r *userRepository) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	return r.updateColumns(ctx, id, map[string]interface{}{"password": passwordHash})
+}
+
+// MarkEmailVerified records that the user proved they own their email address
+func (r *userRepository) MarkEmailVerified(ctx context.Context, id uuid.UUID, verifiedAt time.Time) error {
+	return r.updateColumns(ctx, id, map[string]interface{}{
+		"email_verified":    true,
+		"email_verified_at": verifiedAt,
+	})
+}
+
+// SetDisabled blocks or unblocks a user. disabledAt is nil when enabling.
+func (r *userRepository) SetDisabled(ctx context.Context, id uuid.UUID, disabled bool, disabledAt *time.Time) error {
+	return r.updateColumns(ctx, id, map[string]interface{}{
+		"disabled":    disabled,
+		"disabled_at": disabledAt,
+	})
+}
+
+// SetTOTPSecret stores the secret of a TOTP enrollment that isn't confirmed yet
+func (r *userRepository) SetTOTPSecret(ctx context.Context, id uuid.UUID, secret string) error {
+	return r.updateColumns(ctx, id, map[string]interface{}{"totp_secret": secret})
+}
+
+// EnableTOTP turns TOTP on, starting from the time step used to confirm it
+func (r *userRepository) EnableTOTP(ctx context.Context, id uuid.UUID, step int64) error {
+	return r.updateColumns(ctx, id, map[string]interface{}{
+		"totp_enabled":   true,
+		"totp_last_step": step,
+	})
+}
+
+// DisableTOTP turns TOTP off and forgets the secret
+func (r *userRepository) DisableTOTP(ctx context.Context, id uuid.UUID) error {
+	return r.updateColumns(ctx, id, map[string]interface{}{
+		"totp_enabled":   false,
+		"totp_secret":    "",
+		"totp_last_step": 0,
+	})
+}
+
+// UpdateAccess replaces the roles and directly granted permissions of a user
+func (r *userRepository) UpdateAccess(ctx context.Context, id uuid.UUID, roles, permissions string) error {
+	return r.updateColumns(ctx, id, map[string]interface{}{
+		"roles":       roles,
+		"permissions": permissions,
+	})
+}
+
+// updateColumns writes only the given columns of a user, so concurrent
+// changes to other columns aren't overwritten with stale values
+func (r *userRepository) updateColumns(ctx context.Context, id uuid.UUID, columns map[string]interface{}) error {
+	result := r.db.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ?", id).
+		Updates(columns)
 	if result.Error != nil {
 		return result.Error
 	}
 	if result.RowsAffected == 0 {
-		return errors.New("user not found")
+		return ErrUserNotFound
 	}
 	return nil
 }
@@ -68,7 +149,71 @@ func (r *userRepository) Delete(ctx context.Context, id uuid.UUID) error {
 		return result.Error
 	}
 	if result.RowsAffected == 0 {
-		return errors.New("user not found")
+		return ErrUserNotFound
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// AdvanceTOTPStep records the TOTP time step a user last logged in with. It
+// reports false when that step, or a later one, was already used, so every
+// code is only accepted once.
+func (r *userRepository) AdvanceTOTPStep(ctx context.Context, id uuid.UUID, step int64) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// List returns a page of users matching the filter, newest first, along with
+// the number of users matching it in total
+func (r *userRepository) List(ctx context.Context, filter model.UserFilter) ([]*model.User, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.User{})
+	if filter.Email != "" {
+		pattern := "%" + likeEscaper.Replace(strings.ToLower(filter.Email)) + "%"
+		query = query.Where("LOWER(email) LIKE ? ESCAPE '\\'", pattern)
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *filter.CreatedBefore)
+	}
+	if filter.Disabled != nil {
+		query = query.Where("disabled = ?", *filter.Disabled)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []*model.User
+	query = query.Order("created_at DESC").Order("id").Offset(filter.Offset)
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if err := query.Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// IsActive reports whether the user exists and isn't disabled
+func (r *userRepository) IsActive(ctx context.Context, id uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ? AND disabled = ?", id, false).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// likeEscaper escapes the LIKE wildcards in user input
+var likeEscaper = strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_")
\ No newline at end of file
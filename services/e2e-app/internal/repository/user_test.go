@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tanerincode/e2e-app/internal/config"
+	"github.com/tanerincode/e2e-app/internal/model"
+)
+
+func TestUserWritesOnlyTouchTheirColumns(t *testing.T) {
+	verifiedAt := time.Now().Truncate(time.Second)
+
+	tests := []struct {
+		name  string
+		write func(ctx context.Context, users UserRepository, id uuid.UUID) error
+		// want applies the write to the user as it was created
+		want func(user *model.User)
+	}{
+		{
+			name: "name",
+			write: func(ctx context.Context, users UserRepository, id uuid.UUID) error {
+				return users.UpdateName(ctx, id, "New", "Name")
+			},
+			want: func(user *model.User) { user.FirstName, user.LastName = "New", "Name" },
+		},
+		{
+			name: "password",
+			write: func(ctx context.Context, users UserRepository, id uuid.UUID) error {
+				return users.UpdatePassword(ctx, id, "new-hash")
+			},
+			want: func(user *model.User) { user.Password = "new-hash" },
+		},
+		{
+			name: "email verification",
+			write: func(ctx context.Context, users UserRepository, id uuid.UUID) error {
+				return users.MarkEmailVerified(ctx, id, verifiedAt)
+			},
+			want: func(user *model.User) { user.EmailVerified, user.EmailVerifiedAt = true, &verifiedAt },
+		},
+		{
+			name: "disable",
+			write: func(ctx context.Context, users UserRepository, id uuid.UUID) error {
+				return users.SetDisabled(ctx, id, true, &verifiedAt)
+			},
+			want: func(user *model.User) { user.Disabled, user.DisabledAt = true, &verifiedAt },
+		},
+		{
+			name: "TOTP enrollment",
+			write: func(ctx context.Context, users UserRepository, id uuid.UUID) error {
+				return users.SetTOTPSecret(ctx, id, "NEWSECRET")
+			},
+			want: func(user *model.User) { user.TOTPSecret = "NEWSECRET" },
+		},
+		{
+			name: "TOTP confirmation",
+			write: func(ctx context.Context, users UserRepository, id uuid.UUID) error {
+				return users.EnableTOTP(ctx, id, 42)
+			},
+			want: func(user *model.User) { user.TOTPEnabled, user.TOTPLastStep = true, 42 },
+		},
+		{
+			name: "TOTP removal",
+			write: func(ctx context.Context, users UserRepository, id uuid.UUID) error {
+				return users.DisableTOTP(ctx, id)
+			},
+			want: func(user *model.User) { user.TOTPEnabled, user.TOTPSecret, user.TOTPLastStep = false, "", 0 },
+		},
+		{
+			name: "access",
+			write: func(ctx context.Context, users UserRepository, id uuid.UUID) error {
+				return users.UpdateAccess(ctx, id, "admin", "users:read")
+			},
+			want: func(user *model.User) { user.Roles, user.Permissions = "admin", "users:read" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := NewDB(&config.Config{AppEnv: "development", MockDB: true})
+			if err != nil {
+				t.Fatalf("failed to open mock database: %v", err)
+			}
+			t.Cleanup(func() { CloseDB(db) })
+			users := NewUserRepository(db)
+			ctx := context.Background()
+
+			user := &model.User{
+				Email:        "writes@example.com",
+				Password:     "hash",
+				FirstName:    "Old",
+				LastName:     "Name",
+				TOTPEnabled:  true,
+				TOTPSecret:   "SECRET",
+				TOTPLastStep: 7,
+				Roles:        "user",
+			}
+			if err := users.Create(ctx, user); err != nil {
+				t.Fatalf("failed to create user: %v", err)
+			}
+
+			if err := tt.write(ctx, users, user.ID); err != nil {
+				t.Fatalf("write failed: %v", err)
+			}
+			got, err := users.GetByID(ctx, user.ID)
+			if err != nil {
+				t.Fatalf("failed to reload user: %v", err)
+			}
+			want := *user
+			tt.want(&want)
+			if !sameUser(got, &want) {
+				t.Errorf("got %+v, want %+v", got, &want)
+			}
+
+			if err := tt.write(ctx, users, uuid.New()); !errors.Is(err, ErrUserNotFound) {
+				t.Errorf("write to an unknown user: got %v, want %v", err, ErrUserNotFound)
+			}
+		})
+	}
+}
+
+// sameUser compares the columns the targeted writes manage
+func sameUser(a, b *model.User) bool {
+	sameTime := func(x, y *time.Time) bool {
+		return (x == nil) == (y == nil) && (x == nil || x.Equal(*y))
+	}
+	return a.Email == b.Email && a.Password == b.Password &&
+		a.FirstName == b.FirstName && a.LastName == b.LastName &&
+		a.EmailVerified == b.EmailVerified && sameTime(a.EmailVerifiedAt, b.EmailVerifiedAt) &&
+		a.TOTPEnabled == b.TOTPEnabled && a.TOTPSecret == b.TOTPSecret && a.TOTPLastStep == b.TOTPLastStep &&
+		a.Roles == b.Roles && a.Permissions == b.Permissions &&
+		a.Disabled == b.Disabled && sameTime(a.DisabledAt, b.DisabledAt)
+}
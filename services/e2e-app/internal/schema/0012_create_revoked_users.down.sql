@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_refresh_tokens_revoked_at;
+DROP TABLE IF EXISTS revoked_users;
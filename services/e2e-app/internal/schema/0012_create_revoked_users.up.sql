@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS revoked_users (
+    user_id UUID PRIMARY KEY,
+    revoked_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_users_revoked_at ON revoked_users(revoked_at);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_revoked_at ON refresh_tokens(revoked_at);
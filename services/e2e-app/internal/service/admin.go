@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tanerincode/e2e-app/internal/model"
+)
+
+// ErrCannotModifySelf is returned when an administrator tries to disable or
+// delete their own account, which could leave nobody able to undo it
+var ErrCannotModifySelf = errors.New("administrators can't disable or delete their own account")
+
+// defaultUserPageSize is the page size of user listings that don't ask for one
+const defaultUserPageSize = 20
+
+// ListUsers returns a page of the users matching the request, newest first
+func (s *UserService) ListUsers(ctx context.Context, req *model.ListUsersRequest) (*model.UserPage, error) {
+	page, pageSize := req.Page, req.PageSize
+	if page == 0 {
+		page = 1
+	}
+	if pageSize == 0 {
+		pageSize = defaultUserPageSize
+	}
+
+	users, total, err := s.userRepo.List(ctx, model.UserFilter{
+		Email:         req.Email,
+		CreatedAfter:  req.CreatedAfter,
+		CreatedBefore: req.CreatedBefore,
+		Disabled:      req.Disabled,
+		Offset:        (page - 1) * pageSize,
+		Limit:         pageSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.UserPage{
+		Users:    users,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}, nil
+}
+
+// DisableUser blocks an account from signing in and ends all of its sessions
+func (s *UserService) DisableUser(ctx context.Context, actorID, id uuid.UUID) (*model.User, error) {
+	if actorID == id {
+		return nil, ErrCannotModifySelf
+	}
+
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.Disabled {
+		now := time.Now()
+		if err := s.userRepo.SetDisabled(ctx, user.ID, true, &now); err != nil {
+			return nil, err
+		}
+		user.Disabled = true
+		user.DisabledAt = &now
+	}
+
+	if err := s.refreshTokenRepo.RevokeByUserID(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// EnableUser lets a disabled account sign in again. Sessions ended by
+// disabling it stay revoked.
+func (s *UserService) EnableUser(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Disabled {
+		if err := s.userRepo.SetDisabled(ctx, user.ID, false, nil); err != nil {
+			return nil, err
+		}
+		user.Disabled = false
+		user.DisabledAt = nil
+	}
+	return user, nil
+}
+
+// RevokeSessions ends every session of a user, forcing them to sign in again
+func (s *UserService) RevokeSessions(ctx context.Context, id uuid.UUID) error {
+	if _, err := s.userRepo.GetByID(ctx, id); err != nil {
+		return err
+	}
+	return s.refreshTokenRepo.RevokeByUserID(ctx, id)
+}
+
+// RemoveUser deletes another user's account and ends its sessions
+func (s *UserService) RemoveUser(ctx context.Context, actorID, id uuid.UUID) error {
+	if actorID == id {
+		return ErrCannotModifySelf
+	}
+
+	if err := s.refreshTokenRepo.RevokeByUserID(ctx, id); err != nil {
+		return err
+	}
+	return s.userRepo.Delete(ctx, id)
+}
@@ -2,32 +2,182 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/tanerincode/e2e-app/internal/config"
+	"github.com/tanerincode/e2e-app/internal/federation"
+	"github.com/tanerincode/e2e-app/internal/mail"
+	"github.com/tanerincode/e2e-app/internal/metrics"
 	"github.com/tanerincode/e2e-app/internal/model"
+	"github.com/tanerincode/e2e-app/internal/rbac"
 	"github.com/tanerincode/e2e-app/internal/repository"
+	"github.com/tanerincode/e2e-app/internal/throttle"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	// ErrInvalidRefreshToken is returned when a refresh token can't be parsed,
+	// has expired or is unknown to the token store
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenRevoked is returned when the token family was revoked
+	ErrRefreshTokenRevoked = errors.New("refresh token has been revoked")
+	// ErrRefreshTokenReused is returned when an already rotated refresh token
+	// is presented again. The whole token family is revoked when this happens.
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+	// ErrInvalidToken is returned when an access token can't be verified
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrTokenRevoked is returned when an access token or its session was revoked
+	ErrTokenRevoked = errors.New("token has been revoked")
+	// ErrAccountDisabled is returned when an administrator disabled the account
+	ErrAccountDisabled = errors.New("account is disabled")
+)
+
+// Values of the typ claim. Access, refresh and MFA challenge tokens carry
+// different ones so none can be used in place of another.
+const (
+	typAccess       = "access"
+	typRefresh      = "refresh"
+	typMFAChallenge = "mfa_challenge"
+)
+
 type AuthService interface {
 	Register(ctx context.Context, user *model.User) error
-	Login(ctx context.Context, email, password string) (*model.TokenResponse, error)
+	Login(ctx context.Context, email, password, clientIP string) (*model.LoginResponse, error)
+	VerifyMFA(ctx context.Context, mfaToken, code, clientIP string) (*model.TokenResponse, error)
 	RefreshToken(ctx context.Context, refreshToken string) (*model.TokenResponse, error)
+	ValidateAccessToken(ctx context.Context, accessToken string) (*AccessClaims, error)
+	ValidateAccessTokenFor(ctx context.Context, accessToken, audience string) (*AccessClaims, error)
+	ValidateUserInfoToken(ctx context.Context, accessToken string) (*AccessClaims, error)
+	Logout(ctx context.Context, claims *AccessClaims) error
+	LogoutAll(ctx context.Context, claims *AccessClaims) error
+	VerifyEmail(ctx context.Context, verificationToken string) error
+	ResendVerification(ctx context.Context, email string) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, resetToken, newPassword string) error
+	ForcePasswordReset(ctx context.Context, userID uuid.UUID) error
+	EnrollTOTP(ctx context.Context, userID uuid.UUID) (*model.TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, userID, sessionID uuid.UUID, password, code, clientIP string) error
+	OpenIDConfiguration() *model.OpenIDConfiguration
+	ValidateAuthorizationRequest(ctx context.Context, req *model.AuthorizationRequest) (*model.OAuthClient, error)
+	Authorize(ctx context.Context, req *model.AuthorizationRequest, creds *model.AuthorizationCredentials, clientIP string) (string, *model.MFAChallenge, error)
+	ExchangeToken(ctx context.Context, req *model.OAuthTokenRequest) (*model.TokenResponse, error)
+	UserInfo(ctx context.Context, claims *AccessClaims) (*model.UserInfo, error)
+	Introspect(ctx context.Context, req *model.IntrospectionRequest) (*model.TokenIntrospection, error)
+	IntrospectToken(ctx context.Context, token, tokenTypeHint string) (*model.TokenIntrospection, error)
+	Revocations(ctx context.Context) (*model.Revocations, error)
+	FederatedProviders() []string
+	StartFederatedLogin(ctx context.Context, providerName string) (string, string, error)
+	CompleteFederatedLogin(ctx context.Context, providerName, stateToken, state, code string) (*model.LoginResponse, error)
 }
 
 type authService struct {
-	userRepo repository.UserRepository
-	config   *config.Config
+	userRepo              repository.UserRepository
+	refreshTokenRepo      repository.RefreshTokenRepository
+	revokedTokenRepo      repository.RevokedTokenRepository
+	passwordResetRepo     repository.PasswordResetTokenRepository
+	recoveryCodeRepo      repository.RecoveryCodeRepository
+	oauthClientRepo       repository.OAuthClientRepository
+	authorizationCodeRepo repository.AuthorizationCodeRepository
+	linkedIdentityRepo    repository.LinkedIdentityRepository
+	keys                  *KeySet
+	mailer                mail.Sender
+	federation            *federation.Registry
+	config                *config.Config
+	loginThrottle
 }
 
-func NewAuthService(userRepo repository.UserRepository, cfg *config.Config) AuthService {
+func NewAuthService(
+	userRepo repository.UserRepository,
+	refreshTokenRepo repository.RefreshTokenRepository,
+	revokedTokenRepo repository.RevokedTokenRepository,
+	passwordResetRepo repository.PasswordResetTokenRepository,
+	recoveryCodeRepo repository.RecoveryCodeRepository,
+	oauthClientRepo repository.OAuthClientRepository,
+	authorizationCodeRepo repository.AuthorizationCodeRepository,
+	linkedIdentityRepo repository.LinkedIdentityRepository,
+	keys *KeySet,
+	mailer mail.Sender,
+	limiter *throttle.Limiter,
+	federation *federation.Registry,
+	cfg *config.Config,
+) AuthService {
 	return &authService{
-		userRepo: userRepo,
-		config:   cfg,
+		userRepo:              userRepo,
+		refreshTokenRepo:      refreshTokenRepo,
+		revokedTokenRepo:      revokedTokenRepo,
+		passwordResetRepo:     passwordResetRepo,
+		recoveryCodeRepo:      recoveryCodeRepo,
+		oauthClientRepo:       oauthClientRepo,
+		authorizationCodeRepo: authorizationCodeRepo,
+		linkedIdentityRepo:    linkedIdentityRepo,
+		keys:                  keys,
+		mailer:                mailer,
+		federation:            federation,
+		config:                cfg,
+		loginThrottle:         loginThrottle{limiter: limiter},
+	}
+}
+
+// AccessClaims are the claims carried by an access token. The token ID (jti)
+// is what ends up on the denylist when the token is revoked, and the session
+// ID (sid) is the refresh token family the token was issued from. AMR lists
+// the authentication methods the session was started with, using the values
+// from RFC 8176. Tokens issued through the OAuth endpoints also name the
+// client they were issued to and the granted scope. Roles and Permissions
+// are the user's at the time the token was issued; Permissions already
+// includes the ones granted through roles. Type is always "access"; the
+// subject (sub) repeats UserID.
+type AccessClaims struct {
+	Type        string   `json:"typ"`
+	UserID      string   `json:"user_id"`
+	SessionID   string   `json:"sid"`
+	AMR         []string `json:"amr,omitempty"`
+	ClientID    string   `json:"client_id,omitempty"`
+	Scope       string   `json:"scope,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// HasPermission reports whether the token grants the permission
+func (c *AccessClaims) HasPermission(permission string) bool {
+	for _, granted := range c.Permissions {
+		if granted == permission {
+			return true
+		}
 	}
+	return false
+}
+
+// refreshClaims are the claims carried by a refresh token. The token ID (jti)
+// points at the stored model.RefreshToken and the session ID (sid) is the
+// token family it belongs to. Refresh tokens are only ever read by this
+// service, so they stay signed with the refresh secret rather than the keys
+// in the KeySet. Type is always "refresh" and the audience is this service.
+type refreshClaims struct {
+	Type      string   `json:"typ"`
+	UserID    string   `json:"user_id"`
+	SessionID string   `json:"sid"`
+	AMR       []string `json:"amr,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	Scope     string   `json:"scope,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// session describes the login a token family was started by. Every token
+// issued in the family carries it, so refreshed tokens keep it too.
+type session struct {
+	familyID uuid.UUID
+	amr      []string
+	// clientID and scope are only set for sessions started through the
+	// OAuth endpoints
+	clientID string
+	scope    string
 }
 
 func (s *authService) Register(ctx context.Context, user *model.User) error {
@@ -38,77 +188,392 @@ func (s *authService) Register(ctx context.Context, user *model.User) error {
 	}
 	user.Password = string(hashedPassword)
 
-	return s.userRepo.Create(ctx, user)
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return err
+	}
+
+	// A failed delivery shouldn't fail the registration, the user can ask
+	// for another verification email
+	_ = s.sendVerificationEmail(ctx, user)
+	return nil
 }
 
-func (s *authService) Login(ctx context.Context, email, password string) (*model.TokenResponse, error) {
-	user, err := s.userRepo.GetByEmail(ctx, email)
+func (s *authService) Login(ctx context.Context, email, password, clientIP string) (*model.LoginResponse, error) {
+	user, challenge, err := s.authenticate(ctx, email, password, clientIP)
+	metrics.RecordLogin(metrics.LoginPassword, challenge != nil, err)
 	if err != nil {
 		return nil, err
 	}
+	if challenge != nil {
+		return &model.LoginResponse{Challenge: challenge}, nil
+	}
 
-	if err := comparePasswords(user.Password, password); err != nil {
+	// Every login starts a new token family
+	tokens, err := s.generateTokens(ctx, user, session{
+		familyID: uuid.New(),
+		amr:      []string{amrPassword},
+	})
+	if err != nil {
 		return nil, err
 	}
+	return &model.LoginResponse{Tokens: tokens}, nil
+}
+
+// authenticate checks an email and password. For accounts with two-factor
+// authentication the password alone isn't enough, so they get an MFA
+// challenge instead of the user and their failures are only cleared once the
+// second step succeeds.
+func (s *authService) authenticate(ctx context.Context, email, password, clientIP string) (*model.User, *model.MFAChallenge, error) {
+	if err := s.checkLoginThrottle(ctx, email, clientIP); err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		s.recordLoginFailure(ctx, email, clientIP)
+		return nil, nil, err
+	}
+
+	if err := comparePasswords(user.Password, password); err != nil {
+		s.recordLoginFailure(ctx, email, clientIP)
+		return nil, nil, err
+	}
+
+	if user.Disabled {
+		return nil, nil, ErrAccountDisabled
+	}
+
+	if s.config.RequireEmailVerification && !user.EmailVerified {
+		return nil, nil, ErrEmailNotVerified
+	}
+
+	if user.TOTPEnabled {
+		challenge, err := s.issueMFAChallenge(user, []string{amrPassword})
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, challenge, nil
+	}
 
-	return s.generateTokens(user)
+	s.clearLoginFailures(ctx, email)
+	return user, nil, nil
 }
 
 func (s *authService) RefreshToken(ctx context.Context, refreshToken string) (*model.TokenResponse, error) {
-	token, err := jwt.Parse(refreshToken, func(token *jwt.Token) (interface{}, error) {
-		return []byte(s.config.JWTSecret), nil
-	})
+	claims, err := s.parseRefreshToken(refreshToken)
+	if err != nil {
+		metrics.RecordRefresh(refreshResult(err))
+		return nil, err
+	}
+	// Tokens issued to an OAuth client are only refreshed at /oauth/token,
+	// where the client authenticates and its scope is kept
+	if claims.ClientID != "" {
+		metrics.RecordRefresh(metrics.ResultFailure)
+		return nil, ErrInvalidRefreshToken
+	}
+	tokens, err := s.rotateRefreshToken(ctx, claims)
+	metrics.RecordRefresh(refreshResult(err))
+	return tokens, err
+}
+
+// refreshResult returns the metrics result of a refresh token exchange
+func refreshResult(err error) string {
+	switch {
+	case err == nil:
+		return metrics.ResultSuccess
+	case errors.Is(err, ErrRefreshTokenReused):
+		return metrics.ResultReused
+	default:
+		return metrics.ResultFailure
+	}
+}
+
+// parseRefreshToken verifies a refresh token's signature and expiry
+func (s *authService) parseRefreshToken(refreshToken string) (*refreshClaims, error) {
+	claims := &refreshClaims{}
+	token, err := jwt.ParseWithClaims(refreshToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(s.config.RefreshSecret), nil
+	}, jwt.WithIssuer(s.issuer()), jwt.WithAudience(s.issuer()), jwt.WithIssuedAt(), jwt.WithExpirationRequired())
+	if err != nil || !token.Valid || claims.Type != typRefresh {
+		return nil, ErrInvalidRefreshToken
+	}
+	return claims, nil
+}
+
+// rotateRefreshToken exchanges a parsed refresh token for the next tokens in
+// its family
+func (s *authService) rotateRefreshToken(ctx context.Context, claims *refreshClaims) (*model.TokenResponse, error) {
+	tokenID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	stored, err := s.refreshTokenRepo.GetByID(ctx, tokenID)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if stored.RevokedAt != nil {
+		return nil, ErrRefreshTokenRevoked
+	}
 
-	if err != nil || !token.Valid {
+	rotated, err := s.refreshTokenRepo.MarkRotated(ctx, stored.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// The token was already exchanged once, so either the client or an
+		// attacker holds a stolen copy. Revoke the whole family.
+		if err := s.refreshTokenRepo.RevokeFamily(ctx, stored.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	user, err := s.userRepo.GetByID(ctx, stored.UserID)
+	if err != nil {
 		return nil, err
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
+	// A refreshed session keeps the authentication methods and grant it
+	// started with
+	return s.generateTokens(ctx, user, session{
+		familyID: stored.FamilyID,
+		amr:      claims.AMR,
+		clientID: claims.ClientID,
+		scope:    claims.Scope,
+	})
+}
+
+// ValidateAccessToken verifies an access token sent to this service and
+// checks it against the denylist and the state of the session it belongs to
+func (s *authService) ValidateAccessToken(ctx context.Context, accessToken string) (*AccessClaims, error) {
+	return s.ValidateAccessTokenFor(ctx, accessToken, s.config.JWTExpectedAudience)
+}
+
+// ValidateAccessTokenFor is ValidateAccessToken for a token sent to another
+// service, which must be among the token's audiences. An empty audience
+// accepts any, which only introspection relies on.
+func (s *authService) ValidateAccessTokenFor(ctx context.Context, accessToken, audience string) (*AccessClaims, error) {
+	options := []jwt.ParserOption{jwt.WithIssuer(s.issuer()), jwt.WithIssuedAt(), jwt.WithExpirationRequired()}
+	if audience != "" {
+		options = append(options, jwt.WithAudience(audience))
+	}
+
+	claims := &AccessClaims{}
+	token, err := jwt.ParseWithClaims(accessToken, claims, s.keys.Keyfunc, options...)
+	if err != nil || !token.Valid || claims.Type != typAccess || claims.Subject != claims.UserID {
+		return nil, ErrInvalidToken
+	}
+
+	jti, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	sessionID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	revoked, err := s.revokedTokenRepo.IsRevoked(ctx, jti)
+	if err != nil {
 		return nil, err
 	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
 
-	userID := claims["user_id"].(string)
-	id, err := uuid.Parse(userID)
+	revoked, err = s.refreshTokenRepo.IsFamilyRevoked(ctx, sessionID)
 	if err != nil {
 		return nil, err
 	}
-	user, err := s.userRepo.GetByID(ctx, id)
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	active, err := s.userRepo.IsActive(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
+	if !active {
+		return nil, ErrAccountDisabled
+	}
+
+	return claims, nil
+}
+
+// Logout revokes the access token and the session it was issued from
+func (s *authService) Logout(ctx context.Context, claims *AccessClaims) error {
+	sessionID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	if err := s.refreshTokenRepo.RevokeFamily(ctx, sessionID); err != nil {
+		return err
+	}
+	return s.revokeAccessToken(ctx, claims)
+}
 
-	return s.generateTokens(user)
+// LogoutAll revokes every session of the user the access token belongs to
+func (s *authService) LogoutAll(ctx context.Context, claims *AccessClaims) error {
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	if err := s.refreshTokenRepo.RevokeByUserID(ctx, userID); err != nil {
+		return err
+	}
+	return s.revokeAccessToken(ctx, claims)
 }
 
-func (s *authService) generateTokens(user *model.User) (*model.TokenResponse, error) {
-	// Generate access token
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"exp":     time.Now().Add(s.config.GetJWTExpiration()).Unix(),
+// revokeAccessToken puts an access token on the denylist until it expires
+func (s *authService) revokeAccessToken(ctx context.Context, claims *AccessClaims) error {
+	jti, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return ErrInvalidToken
+	}
+
+	expiresAt := time.Now().Add(s.config.GetJWTExpiration())
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+
+	// Entries are removed by the periodic cleanup once the token has expired
+	return s.revokedTokenRepo.Create(ctx, &model.RevokedToken{
+		JTI:       jti,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
 	})
+}
+
+// Revocations lists the revocations of access tokens that haven't expired
+// yet, for services that verify access tokens themselves. Anything revoked
+// longer than an access token lifetime ago can only concern expired tokens.
+func (s *authService) Revocations(ctx context.Context) (*model.Revocations, error) {
+	now := time.Now()
+	since := now.Add(-s.config.GetJWTExpiration())
+
+	tokenIDs, err := s.revokedTokenRepo.ListActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sessionIDs, err := s.refreshTokenRepo.ListRevokedFamilies(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	users, err := s.refreshTokenRepo.ListRevokedUsers(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Revocations{
+		TokenIDs:    tokenIDs,
+		SessionIDs:  sessionIDs,
+		Users:       users,
+		GeneratedAt: now,
+	}, nil
+}
+
+func (s *authService) generateTokens(ctx context.Context, user *model.User, sess session) (*model.TokenResponse, error) {
+	// Every way of getting tokens ends up here, so this is the one check
+	// that can't be forgotten
+	if user.Disabled {
+		return nil, ErrAccountDisabled
+	}
+
+	now := time.Now()
 
-	accessTokenString, err := accessToken.SignedString([]byte(s.config.JWTSecret))
+	// Generate access token. Roles are read from the user on every issue, so
+	// changes take effect when the session is next refreshed.
+	roles := user.GetRoles()
+	permissions := rbac.Permissions(roles, user.GetPermissions())
+	audience := s.config.GetJWTAudience()
+	// Tokens issued to OAuth clients only open /userinfo. They carry no roles
+	// and none of the first-party audiences, so no API accepts them.
+	if sess.clientID != "" {
+		roles, permissions = nil, nil
+		audience = []string{s.userInfoAudience()}
+	}
+	accessTokenString, err := s.keys.Sign(AccessClaims{
+		Type:        typAccess,
+		UserID:      user.ID.String(),
+		SessionID:   sess.familyID.String(),
+		AMR:         sess.amr,
+		ClientID:    sess.clientID,
+		Scope:       sess.scope,
+		Roles:       roles,
+		Permissions: permissions,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    s.issuer(),
+			Subject:   user.ID.String(),
+			Audience:  audience,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.config.GetJWTExpiration())),
+		},
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	// Persist the refresh token before handing it out
+	stored := &model.RefreshToken{
+		ID:        uuid.New(),
+		FamilyID:  sess.familyID,
+		UserID:    user.ID,
+		ExpiresAt: now.Add(s.config.GetRefreshExpiration()),
+	}
+	if err := s.refreshTokenRepo.Create(ctx, stored); err != nil {
+		return nil, err
+	}
+
 	// Generate refresh token
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"exp":     time.Now().Add(s.config.GetRefreshExpiration()).Unix(),
+	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims{
+		Type:      typRefresh,
+		UserID:    user.ID.String(),
+		SessionID: sess.familyID.String(),
+		AMR:       sess.amr,
+		ClientID:  sess.clientID,
+		Scope:     sess.scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        stored.ID.String(),
+			Issuer:    s.issuer(),
+			Subject:   user.ID.String(),
+			Audience:  jwt.ClaimStrings{s.issuer()},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(stored.ExpiresAt),
+		},
 	})
 
-	refreshTokenString, err := refreshToken.SignedString([]byte(s.config.JWTSecret))
+	refreshTokenString, err := refreshToken.SignedString([]byte(s.config.RefreshSecret))
 	if err != nil {
 		return nil, err
 	}
 
+	flow := metrics.FlowFirstParty
+	if sess.clientID != "" {
+		flow = metrics.FlowOAuth
+	}
+	metrics.RecordTokensIssued(flow)
+
 	return &model.TokenResponse{
 		AccessToken:  accessTokenString,
 		RefreshToken: refreshTokenString,
 		TokenType:    "Bearer",
 		ExpiresIn:    int64(s.config.GetJWTExpiration().Seconds()),
+		Scope:        sess.scope,
 	}, nil
 }
 
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/tanerincode/e2e-app/internal/config"
+	"github.com/tanerincode/e2e-app/internal/model"
+)
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	env.createUser(t, "reuse@example.com")
+	env.createUser(t, "bystander@example.com")
+
+	first := env.login(t, "reuse@example.com")
+	rotated, err := env.auth.RefreshToken(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("first refresh failed: %v", err)
+	}
+	// A session of another user must survive the reuse
+	other := env.login(t, "bystander@example.com")
+
+	// Presenting the rotated token again is reuse
+	if _, err := env.auth.RefreshToken(ctx, first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reusing a rotated refresh token: got %v, want %v", err, ErrRefreshTokenReused)
+	}
+
+	tests := []struct {
+		name string
+		use  func() error
+		want error
+	}{
+		{
+			name: "latest refresh token of the family",
+			use: func() error {
+				_, err := env.auth.RefreshToken(ctx, rotated.RefreshToken)
+				return err
+			},
+			want: ErrRefreshTokenRevoked,
+		},
+		{
+			name: "access token issued by the rotation",
+			use: func() error {
+				_, err := env.auth.ValidateAccessToken(ctx, rotated.AccessToken)
+				return err
+			},
+			want: ErrTokenRevoked,
+		},
+		{
+			name: "access token issued at login",
+			use: func() error {
+				_, err := env.auth.ValidateAccessToken(ctx, first.AccessToken)
+				return err
+			},
+			want: ErrTokenRevoked,
+		},
+		{
+			name: "refresh token of another session",
+			use: func() error {
+				_, err := env.auth.RefreshToken(ctx, other.RefreshToken)
+				return err
+			},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.use(); !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogoutRevokesOnlyItsSession(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	env.createUser(t, "logout@example.com")
+	current := env.login(t, "logout@example.com")
+	other := env.login(t, "logout@example.com")
+
+	claims, err := env.auth.ValidateAccessToken(ctx, current.AccessToken)
+	if err != nil {
+		t.Fatalf("failed to validate access token: %v", err)
+	}
+	if err := env.auth.Logout(ctx, claims); err != nil {
+		t.Fatalf("logout failed: %v", err)
+	}
+
+	// The access token is on the denylist by its jti
+	jti, err := uuid.Parse(claims.ID)
+	if err != nil {
+		t.Fatalf("access token has no jti: %v", err)
+	}
+	if revoked, err := env.auth.revokedTokenRepo.IsRevoked(ctx, jti); err != nil || !revoked {
+		t.Errorf("jti on the denylist: got %v (%v), want true", revoked, err)
+	}
+
+	if _, err := env.auth.ValidateAccessToken(ctx, current.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("logged out access token: got %v, want %v", err, ErrTokenRevoked)
+	}
+	if _, err := env.auth.RefreshToken(ctx, current.RefreshToken); !errors.Is(err, ErrRefreshTokenRevoked) {
+		t.Errorf("logged out refresh token: got %v, want %v", err, ErrRefreshTokenRevoked)
+	}
+	if _, err := env.auth.ValidateAccessToken(ctx, other.AccessToken); err != nil {
+		t.Errorf("access token of another session: %v", err)
+	}
+	if _, err := env.auth.RefreshToken(ctx, other.RefreshToken); err != nil {
+		t.Errorf("refresh token of another session: %v", err)
+	}
+}
+
+func TestLogoutAllRevokesEveryFamily(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	env.createUser(t, "everywhere@example.com")
+	env.createUser(t, "bystander@example.com")
+
+	first := env.login(t, "everywhere@example.com")
+	second := env.login(t, "everywhere@example.com")
+	rotated, err := env.auth.RefreshToken(ctx, second.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	bystander := env.login(t, "bystander@example.com")
+
+	claims, err := env.auth.ValidateAccessToken(ctx, first.AccessToken)
+	if err != nil {
+		t.Fatalf("failed to validate access token: %v", err)
+	}
+	if err := env.auth.LogoutAll(ctx, claims); err != nil {
+		t.Fatalf("logout everywhere failed: %v", err)
+	}
+
+	for name, tokens := range map[string]*model.TokenResponse{"first session": first, "rotated second session": rotated} {
+		if _, err := env.auth.ValidateAccessToken(ctx, tokens.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+			t.Errorf("%s access token: got %v, want %v", name, err, ErrTokenRevoked)
+		}
+		if _, err := env.auth.RefreshToken(ctx, tokens.RefreshToken); !errors.Is(err, ErrRefreshTokenRevoked) {
+			t.Errorf("%s refresh token: got %v, want %v", name, err, ErrRefreshTokenRevoked)
+		}
+	}
+	if _, err := env.auth.ValidateAccessToken(ctx, bystander.AccessToken); err != nil {
+		t.Errorf("access token of another user: %v", err)
+	}
+}
+
+// withSharedSecret signs every kind of token with the same secret, so only
+// the claims tell them apart
+func withSharedSecret(cfg *config.Config) {
+	cfg.RefreshSecret = cfg.JWTSecret
+	cfg.MFAChallengeSecret = cfg.JWTSecret
+}
+
+func TestTokensOnlyWorkAsTheirType(t *testing.T) {
+	for name, configure := range map[string]func(cfg *config.Config){
+		"separate secrets": func(cfg *config.Config) {},
+		"shared secret":    withSharedSecret,
+	} {
+		t.Run(name, func(t *testing.T) {
+			env := newTestEnv(t, configure)
+			ctx := context.Background()
+			env.createUser(t, "plain@example.com")
+			mfaUser := env.createUser(t, "mfa@example.com")
+			env.enrollTOTP(t, mfaUser.ID, time.Now())
+
+			tokens := env.login(t, "plain@example.com")
+			mfaToken := env.challenge(t, "mfa@example.com")
+
+			for kind, token := range map[string]string{"refresh token": tokens.RefreshToken, "MFA challenge": mfaToken} {
+				if _, err := env.auth.ValidateAccessToken(ctx, token); !errors.Is(err, ErrInvalidToken) {
+					t.Errorf("%s as an access token: got %v, want %v", kind, err, ErrInvalidToken)
+				}
+				if _, err := env.auth.ValidateAccessTokenFor(ctx, token, ""); !errors.Is(err, ErrInvalidToken) {
+					t.Errorf("%s as an access token for any audience: got %v, want %v", kind, err, ErrInvalidToken)
+				}
+			}
+			for kind, token := range map[string]string{"access token": tokens.AccessToken, "MFA challenge": mfaToken} {
+				if _, err := env.auth.RefreshToken(ctx, token); !errors.Is(err, ErrInvalidRefreshToken) {
+					t.Errorf("%s as a refresh token: got %v, want %v", kind, err, ErrInvalidRefreshToken)
+				}
+			}
+
+			// The rejected tokens are still good for what they are
+			if _, err := env.auth.ValidateAccessToken(ctx, tokens.AccessToken); err != nil {
+				t.Errorf("access token: %v", err)
+			}
+			if _, err := env.auth.RefreshToken(ctx, tokens.RefreshToken); err != nil {
+				t.Errorf("refresh token: %v", err)
+			}
+		})
+	}
+}
+
+func TestAccessTokenClaims(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	env.createUser(t, "claims@example.com")
+
+	issued, err := env.auth.ValidateAccessToken(ctx, env.login(t, "claims@example.com").AccessToken)
+	if err != nil {
+		t.Fatalf("failed to validate access token: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(claims *AccessClaims)
+		want   error
+	}{
+		{name: "as issued", modify: func(claims *AccessClaims) {}, want: nil},
+		{name: "refresh type", modify: func(claims *AccessClaims) { claims.Type = typRefresh }, want: ErrInvalidToken},
+		{name: "MFA challenge type", modify: func(claims *AccessClaims) { claims.Type = typMFAChallenge }, want: ErrInvalidToken},
+		{name: "no type", modify: func(claims *AccessClaims) { claims.Type = "" }, want: ErrInvalidToken},
+		{name: "wrong issuer", modify: func(claims *AccessClaims) { claims.Issuer = "https://elsewhere.example" }, want: ErrInvalidToken},
+		{name: "wrong audience", modify: func(claims *AccessClaims) { claims.Audience = jwt.ClaimStrings{"elsewhere"} }, want: ErrInvalidToken},
+		{name: "no audience", modify: func(claims *AccessClaims) { claims.Audience = nil }, want: ErrInvalidToken},
+		{name: "not valid yet", modify: func(claims *AccessClaims) { claims.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Hour)) }, want: ErrInvalidToken},
+		{name: "issued in the future", modify: func(claims *AccessClaims) { claims.IssuedAt = jwt.NewNumericDate(time.Now().Add(time.Hour)) }, want: ErrInvalidToken},
+		{name: "expired", modify: func(claims *AccessClaims) { claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute)) }, want: ErrInvalidToken},
+		{name: "no expiry", modify: func(claims *AccessClaims) { claims.ExpiresAt = nil }, want: ErrInvalidToken},
+		{name: "subject of another user", modify: func(claims *AccessClaims) { claims.Subject = uuid.NewString() }, want: ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := *issued
+			tt.modify(&claims)
+			token, err := env.auth.keys.Sign(claims)
+			if err != nil {
+				t.Fatalf("failed to sign token: %v", err)
+			}
+
+			if _, err := env.auth.ValidateAccessToken(ctx, token); !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/tanerincode/e2e-app/internal/federation"
+	"github.com/tanerincode/e2e-app/internal/metrics"
+	"github.com/tanerincode/e2e-app/internal/model"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// amrFederated marks sessions started by signing in at an upstream identity
+// provider. It is not one of the RFC 8176 values, which have no equivalent.
+const amrFederated = "fed"
+
+var (
+	// ErrUnknownFederatedProvider is returned for a provider that isn't configured
+	ErrUnknownFederatedProvider = errors.New("unknown identity provider")
+	// ErrInvalidFederationState is returned when the user comes back from a
+	// provider without a matching login started here
+	ErrInvalidFederationState = errors.New("invalid or expired federated login state")
+	// ErrFederatedEmailNotVerified is returned when the provider doesn't vouch
+	// for the email address of an identity that isn't linked yet
+	ErrFederatedEmailNotVerified = errors.New("the identity provider has not verified this email address")
+	// ErrFederatedLinkNotAllowed is returned when an account with the same email
+	// exists but has not verified it. Linking to it could hand the account of
+	// whoever registered the address to the provider's user, or the other way round.
+	ErrFederatedLinkNotAllowed = errors.New("an account with this email address exists but has not verified it")
+)
+
+// federationStateClaims are the claims of the token that carries a federated
+// login from the redirect to the provider until the user comes back. It is
+// kept in a cookie, which ties the callback to the browser that started the
+// login.
+type federationStateClaims struct {
+	Provider     string `json:"provider"`
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	jwt.RegisteredClaims
+}
+
+// FederatedProviders returns the names of the upstream providers users can
+// sign in with
+func (s *authService) FederatedProviders() []string {
+	names := s.federation.Names()
+	if names == nil {
+		return []string{}
+	}
+	return names
+}
+
+// StartFederatedLogin returns the URL to send the user to for signing in at
+// the provider and the state token to hand back to CompleteFederatedLogin
+func (s *authService) StartFederatedLogin(ctx context.Context, providerName string) (string, string, error) {
+	provider, ok := s.federation.Get(providerName)
+	if !ok {
+		return "", "", ErrUnknownFederatedProvider
+	}
+
+	claims := federationStateClaims{Provider: provider.Name()}
+	for _, value := range []*string{&claims.State, &claims.Nonce, &claims.CodeVerifier} {
+		random, err := randomString(32)
+		if err != nil {
+			return "", "", err
+		}
+		*value = random
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, claims.State, claims.Nonce, claims.CodeVerifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(s.config.GetFederationStateExpiration())),
+	}
+	stateToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.config.FederationStateSecret))
+	if err != nil {
+		return "", "", err
+	}
+
+	return authURL, stateToken, nil
+}
+
+// CompleteFederatedLogin finishes a federated login once the provider sent
+// the user back with state and code. The upstream identity is resolved to a
+// user the same way every time: an identity that is already linked signs in
+// its user, otherwise it is linked to the account with the same verified email
+// or a new account is created for it. Accounts with two-factor authentication
+// still get an MFA challenge.
+func (s *authService) CompleteFederatedLogin(ctx context.Context, providerName, stateToken, state, code string) (*model.LoginResponse, error) {
+	provider, ok := s.federation.Get(providerName)
+	if !ok {
+		return nil, ErrUnknownFederatedProvider
+	}
+
+	claims := &federationStateClaims{}
+	token, err := jwt.ParseWithClaims(stateToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(s.config.FederationStateSecret), nil
+	})
+	if err != nil || !token.Valid || claims.Provider != provider.Name() {
+		return nil, ErrInvalidFederationState
+	}
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(claims.State)) != 1 {
+		return nil, ErrInvalidFederationState
+	}
+
+	identity, err := provider.Exchange(ctx, code, claims.CodeVerifier, claims.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.resolveFederatedUser(ctx, identity)
+	if err == nil && user.Disabled {
+		err = ErrAccountDisabled
+	}
+	metrics.RecordLogin(metrics.LoginFederated, err == nil && user.TOTPEnabled, err)
+	if err != nil {
+		return nil, err
+	}
+
+	amr := []string{amrFederated}
+	if user.TOTPEnabled {
+		challenge, err := s.issueMFAChallenge(user, amr)
+		if err != nil {
+			return nil, err
+		}
+		return &model.LoginResponse{Challenge: challenge}, nil
+	}
+
+	tokens, err := s.generateTokens(ctx, user, session{
+		familyID: uuid.New(),
+		amr:      amr,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &model.LoginResponse{Tokens: tokens}, nil
+}
+
+// resolveFederatedUser returns the user an upstream identity signs in as,
+// linking or creating the account on its first login
+func (s *authService) resolveFederatedUser(ctx context.Context, identity *federation.Identity) (*model.User, error) {
+	if linked, err := s.linkedIdentityRepo.GetBySubject(ctx, identity.Provider, identity.Subject); err == nil {
+		if err := s.linkedIdentityRepo.RecordLogin(ctx, linked.ID, identity.Email); err != nil {
+			log.Printf("Failed to record federated login for user %s: %v", linked.UserID, err)
+		}
+		return s.userRepo.GetByID(ctx, linked.UserID)
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, ErrFederatedEmailNotVerified
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, identity.Email)
+	if err == nil && !user.EmailVerified {
+		return nil, ErrFederatedLinkNotAllowed
+	}
+	if err != nil {
+		if user, err = s.createFederatedUser(ctx, identity); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	if err := s.linkedIdentityRepo.Create(ctx, &model.LinkedIdentity{
+		UserID:      user.ID,
+		Provider:    identity.Provider,
+		Subject:     identity.Subject,
+		Email:       identity.Email,
+		LastLoginAt: &now,
+	}); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// createFederatedUser creates the account for an identity whose email isn't
+// registered yet. The account gets a random password nobody knows; the user
+// can set one through the password reset flow.
+func (s *authService) createFederatedUser(ctx context.Context, identity *federation.Identity) (*model.User, error) {
+	password, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user := &model.User{
+		Email:           identity.Email,
+		Password:        string(hashedPassword),
+		FirstName:       identity.GivenName,
+		LastName:        identity.FamilyName,
+		EmailVerified:   true,
+		EmailVerifiedAt: &now,
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,305 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/tanerincode/e2e-app/internal/config"
+	"github.com/tanerincode/e2e-app/internal/federation"
+	"github.com/tanerincode/e2e-app/internal/model"
+)
+
+const (
+	testProvider = "test"
+	testKeyID    = "test-key"
+)
+
+// testIssuer is an upstream OpenID Connect provider serving discovery, JWKS
+// and a token endpoint. Users sign in through signIn instead of a login page.
+type testIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	logins map[string]issuerLogin
+}
+
+// issuerLogin is a sign in waiting for its authorization code to be redeemed
+type issuerLogin struct {
+	claims        jwt.MapClaims
+	codeChallenge string
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate issuer key: %v", err)
+	}
+	issuer := &testIssuer{key: key, logins: make(map[string]issuerLogin)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", issuer.discovery)
+	mux.HandleFunc("/jwks", issuer.jwks)
+	mux.HandleFunc("/token", issuer.token)
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+func (i *testIssuer) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                i.server.URL,
+		"authorization_endpoint":                i.server.URL + "/authorize",
+		"token_endpoint":                        i.server.URL + "/token",
+		"jwks_uri":                              i.server.URL + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (i *testIssuer) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": testKeyID,
+			"n":   base64.RawURLEncoding.EncodeToString(i.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(i.key.E)).Bytes()),
+		}},
+	})
+}
+
+// token redeems an authorization code from signIn for an ID token, checking
+// the PKCE verifier like a real provider would
+func (i *testIssuer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	i.mu.Lock()
+	login, ok := i.logins[r.PostForm.Get("code")]
+	delete(i.logins, r.PostForm.Get("code"))
+	i.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != login.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, login.claims)
+	token.Header["kid"] = testKeyID
+	idToken, err := token.SignedString(i.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "upstream-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+// signIn signs an upstream user in for the login authURL starts and returns
+// the state and authorization code the provider sends the user back with.
+// nonce replaces the nonce of the authorization request when set.
+func (i *testIssuer) signIn(t *testing.T, authURL, subject, email string, emailVerified bool, nonce string) (string, string) {
+	t.Helper()
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("failed to parse authorization URL: %v", err)
+	}
+	query := parsed.Query()
+	if nonce == "" {
+		nonce = query.Get("nonce")
+	}
+
+	code, err := randomString(16)
+	if err != nil {
+		t.Fatalf("failed to generate authorization code: %v", err)
+	}
+	now := time.Now()
+	i.mu.Lock()
+	i.logins[code] = issuerLogin{
+		claims: jwt.MapClaims{
+			"iss":            i.server.URL,
+			"aud":            query.Get("client_id"),
+			"sub":            subject,
+			"iat":            now.Unix(),
+			"exp":            now.Add(time.Minute).Unix(),
+			"nonce":          nonce,
+			"email":          email,
+			"email_verified": emailVerified,
+			"given_name":     "Federated",
+			"family_name":    "User",
+		},
+		codeChallenge: query.Get("code_challenge"),
+	}
+	i.mu.Unlock()
+	return query.Get("state"), code
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func TestFederatedLogin(t *testing.T) {
+	tests := []struct {
+		name string
+		// localEmail registers a local account first, with a verified email
+		// if localVerified is set
+		localEmail    string
+		localVerified bool
+		email         string
+		emailVerified bool
+		wrongState    bool
+		wrongNonce    bool
+		want          error
+		// wantLinked means the login signs in to the local account
+		wantLinked bool
+	}{
+		{
+			name:          "new user is created",
+			email:         "new@example.com",
+			emailVerified: true,
+		},
+		{
+			name:          "verified local account is linked",
+			localEmail:    "local@example.com",
+			localVerified: true,
+			email:         "local@example.com",
+			emailVerified: true,
+			wantLinked:    true,
+		},
+		{
+			name:          "unverified local account is not linked",
+			localEmail:    "local@example.com",
+			email:         "local@example.com",
+			emailVerified: true,
+			want:          ErrFederatedLinkNotAllowed,
+		},
+		{
+			name:          "email not verified upstream",
+			email:         "new@example.com",
+			emailVerified: false,
+			want:          ErrFederatedEmailNotVerified,
+		},
+		{
+			name:          "state mismatch",
+			email:         "new@example.com",
+			emailVerified: true,
+			wrongState:    true,
+			want:          ErrInvalidFederationState,
+		},
+		{
+			name:          "nonce mismatch",
+			email:         "new@example.com",
+			emailVerified: true,
+			wrongNonce:    true,
+			want:          federation.ErrInvalidNonce,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := newTestIssuer(t)
+			env := newTestEnv(t, func(cfg *config.Config) {
+				cfg.FederatedProviders = []config.FederatedProvider{{
+					Name:         testProvider,
+					IssuerURL:    issuer.server.URL,
+					ClientID:     "e2e-app",
+					ClientSecret: "e2e-app-secret",
+					Scopes:       []string{"openid", "email", "profile"},
+				}}
+			})
+			ctx := context.Background()
+
+			var local *model.User
+			if tt.localEmail != "" {
+				local = env.createUser(t, tt.localEmail)
+				if tt.localVerified {
+					if err := env.db.Model(&model.User{}).Where("id = ?", local.ID).Update("email_verified", true).Error; err != nil {
+						t.Fatalf("failed to verify %s: %v", tt.localEmail, err)
+					}
+				}
+			}
+
+			authURL, stateToken, err := env.auth.StartFederatedLogin(ctx, testProvider)
+			if err != nil {
+				t.Fatalf("failed to start federated login: %v", err)
+			}
+			nonce := ""
+			if tt.wrongNonce {
+				nonce = "another-login"
+			}
+			state, code := issuer.signIn(t, authURL, "upstream-subject", tt.email, tt.emailVerified, nonce)
+			if tt.wrongState {
+				state = "another-login"
+			}
+
+			result, err := env.auth.CompleteFederatedLogin(ctx, testProvider, stateToken, state, code)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+			if tt.want != nil {
+				if _, err := env.auth.linkedIdentityRepo.GetBySubject(ctx, testProvider, "upstream-subject"); err == nil {
+					t.Errorf("refused login linked the upstream identity")
+				}
+				return
+			}
+
+			claims, err := env.auth.ValidateAccessToken(ctx, result.Tokens.AccessToken)
+			if err != nil {
+				t.Fatalf("federated login issued an invalid access token: %v", err)
+			}
+			user, err := env.auth.userRepo.GetByEmail(ctx, tt.email)
+			if err != nil {
+				t.Fatalf("no account for %s: %v", tt.email, err)
+			}
+			if claims.UserID != user.ID.String() {
+				t.Errorf("session belongs to %s, want %s", claims.UserID, user.ID)
+			}
+			if tt.wantLinked && user.ID != local.ID {
+				t.Errorf("login created account %s instead of linking %s", user.ID, local.ID)
+			}
+			if !user.EmailVerified {
+				t.Errorf("account of a verified federated email isn't verified")
+			}
+
+			// The identity is linked now, so it signs in to the same account again
+			authURL, stateToken, err = env.auth.StartFederatedLogin(ctx, testProvider)
+			if err != nil {
+				t.Fatalf("failed to start second federated login: %v", err)
+			}
+			state, code = issuer.signIn(t, authURL, "upstream-subject", tt.email, tt.emailVerified, "")
+			result, err = env.auth.CompleteFederatedLogin(ctx, testProvider, stateToken, state, code)
+			if err != nil {
+				t.Fatalf("second federated login failed: %v", err)
+			}
+			if claims, err := env.auth.ValidateAccessToken(ctx, result.Tokens.AccessToken); err != nil || claims.UserID != user.ID.String() {
+				t.Errorf("second login signed in to %v (%v), want %s", claims, err, user.ID)
+			}
+		})
+	}
+}
@@ -12,6 +12,5 @@ type UserServiceInterface interface {
 	GetUserByID(ctx context.Context, id uint) (*model.User, error)
 	GetUserByEmail(ctx context.Context, email string) (*model.User, error)
 	AuthenticateUser(ctx context.Context, email, password string) (*model.User, error)
-	UpdateUser(ctx context.Context, user *model.User) error
 	DeleteUser(ctx context.Context, id uint) error
 }
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/tanerincode/e2e-app/internal/model"
+	"github.com/tanerincode/e2e-app/internal/rbac"
+)
+
+// Token types reported by introspection, named like the token_type_hint
+// values of RFC 7662
+const (
+	TokenTypeAccess  = "access_token"
+	TokenTypeRefresh = "refresh_token"
+)
+
+// Introspect is the OAuth introspection endpoint (RFC 7662). Only
+// confidential clients may introspect tokens, so the endpoint can't be used
+// to probe for valid tokens anonymously. A client only learns about the
+// tokens issued to it; tokens of other clients and first-party tokens are
+// reported inactive, unless the client is an allow-listed resource server.
+func (s *authService) Introspect(ctx context.Context, req *model.IntrospectionRequest) (*model.TokenIntrospection, error) {
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if client.Public {
+		return nil, newOAuthError("invalid_client", "public clients can't introspect tokens")
+	}
+
+	result, err := s.IntrospectToken(ctx, req.Token, req.TokenTypeHint)
+	if err != nil {
+		return nil, err
+	}
+	if result.Active && result.ClientID != client.ID && !slices.Contains(s.config.GetOAuthIntrospectionClients(), client.ID) {
+		return &model.TokenIntrospection{Active: false}, nil
+	}
+	return result, nil
+}
+
+// IntrospectToken describes an access or refresh token. The hint only
+// decides which kind is tried first. A token that isn't usable any more is
+// reported as inactive rather than as an error.
+func (s *authService) IntrospectToken(ctx context.Context, token, tokenTypeHint string) (*model.TokenIntrospection, error) {
+	introspectors := []func(context.Context, string) (*model.TokenIntrospection, error){
+		s.introspectAccessToken,
+		s.introspectRefreshToken,
+	}
+	if tokenTypeHint == TokenTypeRefresh {
+		introspectors[0], introspectors[1] = introspectors[1], introspectors[0]
+	}
+
+	for _, introspect := range introspectors {
+		result, err := introspect(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+		if result.Active {
+			return result, nil
+		}
+	}
+	return &model.TokenIntrospection{Active: false}, nil
+}
+
+// introspectAccessToken describes an access token if it passes the same
+// checks as ValidateAccessToken, whatever its audience
+func (s *authService) introspectAccessToken(ctx context.Context, token string) (*model.TokenIntrospection, error) {
+	claims, err := s.ValidateAccessTokenFor(ctx, token, "")
+	if err != nil {
+		if errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrTokenRevoked) || errors.Is(err, ErrAccountDisabled) {
+			return &model.TokenIntrospection{Active: false}, nil
+		}
+		return nil, err
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return &model.TokenIntrospection{Active: false}, nil
+	}
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return &model.TokenIntrospection{Active: false}, nil
+	}
+
+	result := &model.TokenIntrospection{
+		Active:      true,
+		TokenType:   TokenTypeAccess,
+		Subject:     claims.UserID,
+		Username:    user.Email,
+		Issuer:      claims.Issuer,
+		Audience:    claims.Audience,
+		TokenID:     claims.ID,
+		SessionID:   claims.SessionID,
+		ClientID:    claims.ClientID,
+		Scope:       claims.Scope,
+		AMR:         claims.AMR,
+		Roles:       claims.Roles,
+		Permissions: claims.Permissions,
+	}
+	if claims.IssuedAt != nil {
+		result.IssuedAt = claims.IssuedAt.Unix()
+	}
+	if claims.ExpiresAt != nil {
+		result.ExpiresAt = claims.ExpiresAt.Unix()
+	}
+	return result, nil
+}
+
+// introspectRefreshToken describes a refresh token that can still be
+// exchanged. Refresh tokens don't carry roles, so the user's current ones are
+// reported.
+func (s *authService) introspectRefreshToken(ctx context.Context, token string) (*model.TokenIntrospection, error) {
+	claims, err := s.parseRefreshToken(token)
+	if err != nil {
+		return &model.TokenIntrospection{Active: false}, nil
+	}
+
+	tokenID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return &model.TokenIntrospection{Active: false}, nil
+	}
+	stored, err := s.refreshTokenRepo.GetByID(ctx, tokenID)
+	if err != nil || stored.RevokedAt != nil || stored.RotatedAt != nil {
+		return &model.TokenIntrospection{Active: false}, nil
+	}
+
+	user, err := s.userRepo.GetByID(ctx, stored.UserID)
+	if err != nil || user.Disabled {
+		return &model.TokenIntrospection{Active: false}, nil
+	}
+
+	roles := user.GetRoles()
+	result := &model.TokenIntrospection{
+		Active:      true,
+		TokenType:   TokenTypeRefresh,
+		Subject:     user.ID.String(),
+		Username:    user.Email,
+		Issuer:      claims.Issuer,
+		Audience:    claims.Audience,
+		TokenID:     claims.ID,
+		SessionID:   stored.FamilyID.String(),
+		ClientID:    claims.ClientID,
+		Scope:       claims.Scope,
+		AMR:         claims.AMR,
+		Roles:       roles,
+		Permissions: rbac.Permissions(roles, user.GetPermissions()),
+		ExpiresAt:   stored.ExpiresAt.Unix(),
+	}
+	if claims.IssuedAt != nil {
+		result.IssuedAt = claims.IssuedAt.Unix()
+	}
+	return result, nil
+}
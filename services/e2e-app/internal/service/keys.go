@@ -0,0 +1,254 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/tanerincode/e2e-app/internal/config"
+)
+
+// SigningKey is an asymmetric key access tokens are signed or verified with
+type SigningKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	PublicKey crypto.PublicKey
+	// PrivateKey is only set for the key new tokens are signed with
+	PrivateKey crypto.Signer
+}
+
+// JWK is the JSON Web Key representation of a public verification key
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JWKS is the document served on /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// KeySet holds the key access tokens are signed with and every key they may
+// still be verified with. Rotating keys means signing with a new key while
+// keeping the previous one around for verification until its tokens expire.
+//
+// When no signing key file is configured the set falls back to HS256 with
+// the shared JWT secret and publishes no keys.
+type KeySet struct {
+	signing *SigningKey
+	keys    map[string]*SigningKey
+	secret  []byte
+}
+
+// NewKeySet loads the signing and verification keys configured in cfg
+func NewKeySet(cfg *config.Config) (*KeySet, error) {
+	ks := &KeySet{
+		keys: make(map[string]*SigningKey),
+	}
+
+	if cfg.JWTSigningKeyFile == "" {
+		ks.secret = []byte(cfg.JWTSecret)
+		return ks, nil
+	}
+
+	signing, err := loadKeyFile(cfg.JWTSigningKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if signing.PrivateKey == nil {
+		return nil, fmt.Errorf("signing key %s does not contain a private key", cfg.JWTSigningKeyFile)
+	}
+	ks.signing = signing
+	ks.keys[signing.ID] = signing
+
+	for _, path := range cfg.GetJWTVerificationKeyFiles() {
+		key, err := loadKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		// Verification keys never sign, even if the file holds a private key
+		key.PrivateKey = nil
+		if _, exists := ks.keys[key.ID]; !exists {
+			ks.keys[key.ID] = key
+		}
+	}
+
+	return ks, nil
+}
+
+// Sign signs the claims with the active signing key
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if ks.signing == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ks.secret)
+	}
+
+	token := jwt.NewWithClaims(ks.signing.Method, claims)
+	token.Header["kid"] = ks.signing.ID
+	return token.SignedString(ks.signing.PrivateKey)
+}
+
+// Algorithm returns the JWS algorithm new tokens are signed with
+func (ks *KeySet) Algorithm() string {
+	if ks.signing == nil {
+		return jwt.SigningMethodHS256.Alg()
+	}
+	return ks.signing.Method.Alg()
+}
+
+// Asymmetric reports whether tokens are signed with an asymmetric key.
+// OpenID Connect needs one: clients can only verify ID tokens against
+// published keys, never against the shared secret.
+func (ks *KeySet) Asymmetric() bool {
+	return ks.signing != nil
+}
+
+// Keyfunc resolves the verification key for a token by its kid header
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if ks.signing == nil {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return ks.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key: %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.PublicKey, nil
+}
+
+// JWKS returns the public verification keys as a JSON Web Key Set
+func (ks *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: make([]JWK, 0, len(ks.keys))}
+	for _, key := range ks.keys {
+		jwk, err := toJWK(key)
+		if err != nil {
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	// Keep the order stable between requests
+	sort.Slice(jwks.Keys, func(i, j int) bool {
+		return jwks.Keys[i].KeyID < jwks.Keys[j].KeyID
+	})
+	return jwks
+}
+
+// loadKeyFile reads a PEM encoded RSA or Ed25519 key. Private keys are
+// accepted in PKCS#1 and PKCS#8 form, public keys in PKCS#1 and PKIX form.
+func loadKeyFile(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file %s: %w", path, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q in %s", block.Type, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key file %s: %w", path, err)
+	}
+
+	key := &SigningKey{}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.PublicKey, key.PrivateKey = jwt.SigningMethodRS256, &k.PublicKey, k
+	case *rsa.PublicKey:
+		key.Method, key.PublicKey = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.PublicKey, key.PrivateKey = jwt.SigningMethodEdDSA, k.Public(), k
+	case ed25519.PublicKey:
+		key.Method, key.PublicKey = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T in %s", parsed, path)
+	}
+
+	key.ID, err = keyThumbprint(key)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// keyThumbprint derives the key ID from the RFC 7638 JWK thumbprint, so the
+// same key always gets the same kid without any extra configuration
+func keyThumbprint(key *SigningKey) (string, error) {
+	jwk, err := toJWK(key)
+	if err != nil {
+		return "", err
+	}
+
+	// Members in lexicographic order as required by RFC 7638
+	var members string
+	switch jwk.KeyType {
+	case "RSA":
+		members = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, jwk.E, jwk.N)
+	case "OKP":
+		members = fmt.Sprintf(`{"crv":%q,"kty":"OKP","x":%q}`, jwk.Curve, jwk.X)
+	}
+
+	sum := sha256.Sum256([]byte(members))
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+func toJWK(key *SigningKey) (JWK, error) {
+	jwk := JWK{
+		Use:       "sig",
+		KeyID:     key.ID,
+		Algorithm: key.Method.Alg(),
+	}
+
+	switch pub := key.PublicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	default:
+		return JWK{}, errors.New("unsupported public key type")
+	}
+
+	return jwk, nil
+}
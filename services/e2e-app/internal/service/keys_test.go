@@ -0,0 +1,343 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/tanerincode/e2e-app/internal/config"
+)
+
+// writeKeyFile writes der as a PEM block to a file in the test's temporary
+// directory and returns its path
+func writeKeyFile(t *testing.T, name, blockType string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	return key
+}
+
+func newEd25519Key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate Ed25519 key: %v", err)
+	}
+	return key
+}
+
+// pkcs8File writes a private key in PKCS#8 form
+func pkcs8File(t *testing.T, name string, key interface{}) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to encode %s: %v", name, err)
+	}
+	return writeKeyFile(t, name, "PRIVATE KEY", der)
+}
+
+// pkixFile writes a public key in PKIX form
+func pkixFile(t *testing.T, name string, key interface{}) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("failed to encode %s: %v", name, err)
+	}
+	return writeKeyFile(t, name, "PUBLIC KEY", der)
+}
+
+func TestLoadKeyFile(t *testing.T) {
+	rsaKey := newRSAKey(t)
+	edKey := newEd25519Key(t)
+	notPEM := filepath.Join(t.TempDir(), "key.txt")
+	if err := os.WriteFile(notPEM, []byte("not a key"), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", notPEM, err)
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		wantMethod  jwt.SigningMethod
+		wantPrivate bool
+		wantErr     string
+	}{
+		{
+			name:        "RSA private key in PKCS#1 form",
+			path:        writeKeyFile(t, "rsa-pkcs1.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)),
+			wantMethod:  jwt.SigningMethodRS256,
+			wantPrivate: true,
+		},
+		{
+			name:        "RSA private key in PKCS#8 form",
+			path:        pkcs8File(t, "rsa-pkcs8.pem", rsaKey),
+			wantMethod:  jwt.SigningMethodRS256,
+			wantPrivate: true,
+		},
+		{
+			name:       "RSA public key in PKCS#1 form",
+			path:       writeKeyFile(t, "rsa-public-pkcs1.pem", "RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)),
+			wantMethod: jwt.SigningMethodRS256,
+		},
+		{
+			name:       "RSA public key in PKIX form",
+			path:       pkixFile(t, "rsa-public.pem", &rsaKey.PublicKey),
+			wantMethod: jwt.SigningMethodRS256,
+		},
+		{
+			name:        "Ed25519 private key",
+			path:        pkcs8File(t, "ed25519.pem", edKey),
+			wantMethod:  jwt.SigningMethodEdDSA,
+			wantPrivate: true,
+		},
+		{
+			name:       "Ed25519 public key",
+			path:       pkixFile(t, "ed25519-public.pem", edKey.Public()),
+			wantMethod: jwt.SigningMethodEdDSA,
+		},
+		{
+			name:    "not PEM",
+			path:    notPEM,
+			wantErr: "no PEM data found",
+		},
+		{
+			name:    "missing file",
+			path:    filepath.Join(t.TempDir(), "missing.pem"),
+			wantErr: "failed to read key file",
+		},
+		{
+			name:    "unsupported block",
+			path:    writeKeyFile(t, "certificate.pem", "CERTIFICATE", []byte("not a certificate")),
+			wantErr: "unsupported PEM block",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := loadKeyFile(tt.path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to load key: %v", err)
+			}
+			if key.Method != tt.wantMethod {
+				t.Errorf("method %v, want %v", key.Method.Alg(), tt.wantMethod.Alg())
+			}
+			if (key.PrivateKey != nil) != tt.wantPrivate {
+				t.Errorf("private key loaded: %v, want %v", key.PrivateKey != nil, tt.wantPrivate)
+			}
+			thumbprint, err := keyThumbprint(key)
+			if err != nil || key.ID != thumbprint {
+				t.Errorf("kid %q, want the thumbprint %q (%v)", key.ID, thumbprint, err)
+			}
+		})
+	}
+}
+
+func TestKeyThumbprint(t *testing.T) {
+	decode := func(s string) []byte {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			t.Fatalf("failed to decode %q: %v", s, err)
+		}
+		return b
+	}
+
+	tests := []struct {
+		name string
+		key  *SigningKey
+		want string
+	}{
+		{
+			// RFC 7638, section 3.1
+			name: "RSA",
+			key: &SigningKey{
+				Method: jwt.SigningMethodRS256,
+				PublicKey: &rsa.PublicKey{
+					N: new(big.Int).SetBytes(decode("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")),
+					E: 65537,
+				},
+			},
+			want: "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs",
+		},
+		{
+			// RFC 8037, appendix A.3
+			name: "Ed25519",
+			key: &SigningKey{
+				Method:    jwt.SigningMethodEdDSA,
+				PublicKey: ed25519.PublicKey(decode("11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo")),
+			},
+			want: "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := keyThumbprint(tt.key)
+			if err != nil {
+				t.Fatalf("failed to compute thumbprint: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// newKeySet loads a key set signing with signingKeyFile and verifying with
+// verificationKeyFiles as well
+func newKeySet(t *testing.T, signingKeyFile string, verificationKeyFiles ...string) *KeySet {
+	t.Helper()
+
+	ks, err := NewKeySet(&config.Config{
+		JWTSigningKeyFile:       signingKeyFile,
+		JWTVerificationKeyFiles: strings.Join(verificationKeyFiles, ","),
+	})
+	if err != nil {
+		t.Fatalf("failed to load key set: %v", err)
+	}
+	return ks
+}
+
+func TestKeyRotation(t *testing.T) {
+	previousFile := pkcs8File(t, "previous.pem", newRSAKey(t))
+	currentFile := pkcs8File(t, "current.pem", newEd25519Key(t))
+
+	claims := jwt.RegisteredClaims{Subject: "user", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))}
+	sign := func(ks *KeySet) string {
+		token, err := ks.Sign(claims)
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+		return token
+	}
+	previousToken := sign(newKeySet(t, previousFile))
+	rotated := newKeySet(t, currentFile, previousFile)
+	currentToken := sign(rotated)
+
+	// A token with the kid of the previous key, but signed with HMAC
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	forged.Header["kid"] = newKeySet(t, previousFile).signing.ID
+	forgedToken, err := forged.SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("failed to sign forged token: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		keys  *KeySet
+		token string
+		want  bool
+	}{
+		{name: "token of the current key", keys: rotated, token: currentToken, want: true},
+		{name: "token of the previous key kept for verification", keys: rotated, token: previousToken, want: true},
+		{name: "token of a retired key", keys: newKeySet(t, currentFile), token: previousToken, want: false},
+		{name: "HMAC token naming an RSA key", keys: rotated, token: forgedToken, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := jwt.Parse(tt.token, tt.keys.Keyfunc)
+			if (err == nil) != tt.want {
+				t.Errorf("verified: %v (%v), want %v", err == nil, err, tt.want)
+			}
+		})
+	}
+
+	if got, want := rotated.Algorithm(), jwt.SigningMethodEdDSA.Alg(); got != want {
+		t.Errorf("rotated set signs with %s, want %s", got, want)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	rsaKey := newRSAKey(t)
+	edKey := newEd25519Key(t)
+	ks := newKeySet(t, pkcs8File(t, "current.pem", edKey), pkixFile(t, "previous.pem", &rsaKey.PublicKey))
+
+	jwks := ks.JWKS()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("got %d keys, want 2", len(jwks.Keys))
+	}
+	if jwks.Keys[0].KeyID > jwks.Keys[1].KeyID {
+		t.Errorf("keys aren't sorted by kid: %q, %q", jwks.Keys[0].KeyID, jwks.Keys[1].KeyID)
+	}
+
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "sig" {
+			t.Errorf("key %s: use %q, want sig", jwk.KeyID, jwk.Use)
+		}
+		if key, ok := ks.keys[jwk.KeyID]; !ok {
+			t.Errorf("published kid %s isn't in the set", jwk.KeyID)
+		} else if thumbprint, _ := keyThumbprint(key); jwk.KeyID != thumbprint {
+			t.Errorf("published kid %s, want the thumbprint %s", jwk.KeyID, thumbprint)
+		}
+
+		switch jwk.KeyType {
+		case "RSA":
+			n, _ := base64.RawURLEncoding.DecodeString(jwk.N)
+			if jwk.Algorithm != "RS256" || new(big.Int).SetBytes(n).Cmp(rsaKey.N) != 0 || jwk.E != "AQAB" {
+				t.Errorf("RSA key %+v doesn't match the loaded key", jwk)
+			}
+		case "OKP":
+			x, _ := base64.RawURLEncoding.DecodeString(jwk.X)
+			if jwk.Algorithm != "EdDSA" || jwk.Curve != "Ed25519" || !edKey.Public().(ed25519.PublicKey).Equal(ed25519.PublicKey(x)) {
+				t.Errorf("Ed25519 key %+v doesn't match the loaded key", jwk)
+			}
+		default:
+			t.Errorf("unexpected key type %q", jwk.KeyType)
+		}
+	}
+
+	// Only public members are published
+	encoded, err := json.Marshal(jwks)
+	if err != nil {
+		t.Fatalf("failed to encode JWKS: %v", err)
+	}
+	var raw struct {
+		Keys []map[string]interface{} `json:"keys"`
+	}
+	if err := json.Unmarshal(encoded, &raw); err != nil {
+		t.Fatalf("failed to decode JWKS: %v", err)
+	}
+	for _, key := range raw.Keys {
+		for _, private := range []string{"d", "p", "q", "dp", "dq", "qi"} {
+			if _, ok := key[private]; ok {
+				t.Errorf("key %v publishes private member %q", key["kid"], private)
+			}
+		}
+	}
+
+	// The shared secret fallback has nothing to publish
+	if keys := newKeySet(t, "").JWKS().Keys; len(keys) != 0 {
+		t.Errorf("HS256 key set published %d keys", len(keys))
+	}
+}
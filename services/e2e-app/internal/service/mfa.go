@@ -0,0 +1,331 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/tanerincode/e2e-app/internal/metrics"
+	"github.com/tanerincode/e2e-app/internal/model"
+	"github.com/tanerincode/e2e-app/internal/totp"
+)
+
+// Authentication method references (RFC 8176) put in the amr claim
+const (
+	amrPassword = "pwd"
+	amrOTP      = "otp"
+	amrMFA      = "mfa"
+)
+
+// recoveryCodeCount is the number of recovery codes issued at a time
+const recoveryCodeCount = 10
+
+var (
+	// ErrTOTPAlreadyEnabled is returned when enrolling an account that already uses TOTP
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrTOTPNotEnrolled is returned when confirming or disabling TOTP without an enrollment
+	ErrTOTPNotEnrolled = errors.New("two-factor authentication has not been set up")
+	// ErrInvalidMFACode is returned when neither a TOTP code nor a recovery code matches
+	ErrInvalidMFACode = errors.New("invalid two-factor authentication code")
+	// ErrInvalidMFAToken is returned when an MFA challenge token can't be verified or has expired
+	ErrInvalidMFAToken = errors.New("invalid or expired MFA token")
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// mfaChallengeClaims are the claims carried by the token handed out between
+// the two login steps. AMR is how the first step was authenticated. Type is
+// always "mfa_challenge" and both issuer and audience are this service. The
+// token ID (jti) goes on the denylist once the login completes, so each
+// challenge signs in at most once.
+type mfaChallengeClaims struct {
+	Type   string   `json:"typ"`
+	UserID string   `json:"user_id"`
+	AMR    []string `json:"amr,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// EnrollTOTP starts TOTP enrollment by generating a new secret. TOTP is only
+// enforced once ConfirmTOTP has seen a valid code for that secret.
+func (s *authService) EnrollTOTP(ctx context.Context, userID uuid.UUID) (*model.TOTPEnrollment, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.SetTOTPSecret(ctx, user.ID, secret); err != nil {
+		return nil, err
+	}
+
+	return &model.TOTPEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(s.config.MFAIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP turns TOTP on once the user proves their authenticator works
+// and returns a fresh set of recovery codes
+func (s *authService) ConfirmTOTP(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTOTPNotEnrolled
+	}
+
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, err := s.replaceRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.EnableTOTP(ctx, user.ID, step); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP turns TOTP off after checking the password and a second
+// factor again, then revokes every session except the one making the change.
+// The code is a TOTP code or a recovery code, both single-use as at login;
+// an enrollment that was never confirmed needs only the password.
+func (s *authService) DisableTOTP(ctx context.Context, userID, sessionID uuid.UUID, password, code, clientIP string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.recheckPassword(ctx, user, password, clientIP); err != nil {
+		return err
+	}
+	if !user.TOTPEnabled && user.TOTPSecret == "" {
+		return ErrTOTPNotEnrolled
+	}
+
+	if user.TOTPEnabled {
+		ok, err := s.checkSecondFactor(ctx, user, code)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			s.recordLoginFailure(ctx, user.Email, clientIP)
+			return ErrInvalidMFACode
+		}
+	}
+
+	if err := s.userRepo.DisableTOTP(ctx, user.ID); err != nil {
+		return err
+	}
+	if err := s.recoveryCodeRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		return err
+	}
+
+	// Sessions signed in with the second factor shouldn't outlive it
+	return s.refreshTokenRepo.RevokeOtherFamilies(ctx, user.ID, sessionID)
+}
+
+// VerifyMFA finishes a login that was answered with an MFA challenge
+func (s *authService) VerifyMFA(ctx context.Context, mfaToken, code, clientIP string) (*model.TokenResponse, error) {
+	user, amr, err := s.completeMFA(ctx, mfaToken, code, clientIP)
+	metrics.RecordLogin(metrics.LoginMFA, false, err)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.generateTokens(ctx, user, session{
+		familyID: uuid.New(),
+		amr:      amr,
+	})
+}
+
+// completeMFA checks the second factor for an MFA challenge and returns the
+// user it was issued for along with the authentication methods of both
+// steps. Wrong codes count towards the same lockouts as wrong passwords.
+func (s *authService) completeMFA(ctx context.Context, mfaToken, code, clientIP string) (*model.User, []string, error) {
+	claims := &mfaChallengeClaims{}
+	token, err := jwt.ParseWithClaims(mfaToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(s.config.MFAChallengeSecret), nil
+	}, jwt.WithIssuer(s.issuer()), jwt.WithAudience(s.issuer()), jwt.WithIssuedAt(), jwt.WithExpirationRequired())
+	if err != nil || !token.Valid || claims.Type != typMFAChallenge {
+		return nil, nil, ErrInvalidMFAToken
+	}
+
+	challengeID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return nil, nil, ErrInvalidMFAToken
+	}
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, nil, ErrInvalidMFAToken
+	}
+
+	// A challenge that already signed in can't be used to try more codes
+	used, err := s.revokedTokenRepo.IsRevoked(ctx, challengeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if used {
+		return nil, nil, ErrInvalidMFAToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil || !user.TOTPEnabled {
+		return nil, nil, ErrInvalidMFAToken
+	}
+	if user.Disabled {
+		return nil, nil, ErrAccountDisabled
+	}
+
+	if err := s.checkLoginThrottle(ctx, user.Email, clientIP); err != nil {
+		return nil, nil, err
+	}
+
+	ok, err := s.checkSecondFactor(ctx, user, code)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		s.recordLoginFailure(ctx, user.Email, clientIP)
+		return nil, nil, ErrInvalidMFACode
+	}
+
+	// Of two requests completing the same challenge at once only one wins
+	consumed, err := s.revokedTokenRepo.Consume(ctx, &model.RevokedToken{
+		JTI:       challengeID,
+		UserID:    user.ID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if !consumed {
+		return nil, nil, ErrInvalidMFAToken
+	}
+
+	s.clearLoginFailures(ctx, user.Email)
+	return user, mfaAMR(claims.AMR), nil
+}
+
+// mfaAMR returns the authentication methods of a two-factor login whose first
+// step used firstFactor. Challenges issued before the first factor was
+// recorded all came from a password login.
+func mfaAMR(firstFactor []string) []string {
+	if len(firstFactor) == 0 {
+		firstFactor = []string{amrPassword}
+	}
+	amr := append([]string{}, firstFactor...)
+	return append(amr, amrOTP, amrMFA)
+}
+
+// issueMFAChallenge creates the token that links the two login steps.
+// firstFactor is how the user authenticated in the first step.
+func (s *authService) issueMFAChallenge(user *model.User, firstFactor []string) (*model.MFAChallenge, error) {
+	now := time.Now()
+	expiration := s.config.GetMFAChallengeExpiration()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, mfaChallengeClaims{
+		Type:   typMFAChallenge,
+		UserID: user.ID.String(),
+		AMR:    firstFactor,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    s.issuer(),
+			Subject:   user.ID.String(),
+			Audience:  jwt.ClaimStrings{s.issuer()},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiration)),
+		},
+	})
+
+	tokenString, err := token.SignedString([]byte(s.config.MFAChallengeSecret))
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.MFAChallenge{
+		MFARequired: true,
+		MFAToken:    tokenString,
+		ExpiresIn:   int64(expiration.Seconds()),
+	}, nil
+}
+
+// checkSecondFactor accepts either a TOTP code that hasn't been used yet or an
+// unused recovery code
+func (s *authService) checkSecondFactor(ctx context.Context, user *model.User, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+
+	if step, ok := totp.Validate(user.TOTPSecret, code, time.Now()); ok {
+		return s.userRepo.AdvanceTOTPStep(ctx, user.ID, step)
+	}
+
+	return s.recoveryCodeRepo.Consume(ctx, user.ID, hashRecoveryCode(code))
+}
+
+// replaceRecoveryCodes stores a new set of recovery codes for a user,
+// invalidating the previous set, and returns the codes in plain text
+func (s *authService) replaceRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]*model.RecoveryCode, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		records = append(records, &model.RecoveryCode{
+			UserID:   userID,
+			CodeHash: hashRecoveryCode(code),
+		})
+	}
+
+	if err := s.recoveryCodeRepo.ReplaceForUser(ctx, userID, records); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode returns a random code formatted as two groups of five
+// characters, e.g. "k3j9d-q2m4x"
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// hashRecoveryCode normalizes a recovery code the way users tend to mistype
+// it and returns the hex encoded SHA-256 hash that is stored
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,300 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/tanerincode/e2e-app/internal/totp"
+)
+
+// enrollTOTP turns TOTP on for a user, confirming it with the code of the
+// time step before at, and returns the secret and recovery codes
+func (e *testEnv) enrollTOTP(t *testing.T, userID uuid.UUID, at time.Time) (string, []string) {
+	t.Helper()
+	ctx := context.Background()
+
+	enrollment, err := e.auth.EnrollTOTP(ctx, userID)
+	if err != nil {
+		t.Fatalf("failed to enroll TOTP: %v", err)
+	}
+	code := totpCode(t, enrollment.Secret, at.Add(-totp.Period))
+	recoveryCodes, err := e.auth.ConfirmTOTP(ctx, userID, code)
+	if err != nil {
+		t.Fatalf("failed to confirm TOTP: %v", err)
+	}
+	return enrollment.Secret, recoveryCodes
+}
+
+// challenge logs a user with TOTP in and returns the MFA token
+func (e *testEnv) challenge(t *testing.T, email string) string {
+	t.Helper()
+
+	result, err := e.auth.Login(context.Background(), email, testPassword, testClientIP)
+	if err != nil {
+		t.Fatalf("failed to log %s in: %v", email, err)
+	}
+	if result.Challenge == nil {
+		t.Fatalf("login of %s didn't ask for a second factor", email)
+	}
+	return result.Challenge.MFAToken
+}
+
+func totpCode(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+
+	code, err := totp.Code(secret, at)
+	if err != nil {
+		t.Fatalf("failed to generate TOTP code: %v", err)
+	}
+	return code
+}
+
+func TestSecondFactorsAreSingleUse(t *testing.T) {
+	tests := []struct {
+		name string
+		// first and second are the codes presented on two consecutive
+		// challenges, given the TOTP secret, the recovery codes and the time
+		// the codes are generated for
+		first, second func(t *testing.T, secret string, recoveryCodes []string, at time.Time) string
+		wantFirst     error
+		wantSecond    error
+	}{
+		{
+			name: "TOTP code replayed on a new challenge",
+			first: func(t *testing.T, secret string, _ []string, at time.Time) string {
+				return totpCode(t, secret, at)
+			},
+			second: func(t *testing.T, secret string, _ []string, at time.Time) string {
+				return totpCode(t, secret, at)
+			},
+			wantFirst:  nil,
+			wantSecond: ErrInvalidMFACode,
+		},
+		{
+			name: "TOTP code of the step used to confirm enrollment",
+			first: func(t *testing.T, secret string, _ []string, at time.Time) string {
+				return totpCode(t, secret, at.Add(-totp.Period))
+			},
+			second: func(t *testing.T, secret string, _ []string, at time.Time) string {
+				return totpCode(t, secret, at)
+			},
+			wantFirst:  ErrInvalidMFACode,
+			wantSecond: nil,
+		},
+		{
+			name: "recovery code used twice",
+			first: func(t *testing.T, _ string, recoveryCodes []string, _ time.Time) string {
+				return recoveryCodes[0]
+			},
+			second: func(t *testing.T, _ string, recoveryCodes []string, _ time.Time) string {
+				return recoveryCodes[0]
+			},
+			wantFirst:  nil,
+			wantSecond: ErrInvalidMFACode,
+		},
+		{
+			name: "different recovery codes",
+			first: func(t *testing.T, _ string, recoveryCodes []string, _ time.Time) string {
+				return recoveryCodes[0]
+			},
+			second: func(t *testing.T, _ string, recoveryCodes []string, _ time.Time) string {
+				return recoveryCodes[1]
+			},
+			wantFirst:  nil,
+			wantSecond: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			ctx := context.Background()
+			user := env.createUser(t, "mfa@example.com")
+			// Codes are generated for a fixed time so a step boundary passing
+			// mid-test doesn't change them
+			at := time.Now()
+			secret, recoveryCodes := env.enrollTOTP(t, user.ID, at)
+
+			_, err := env.auth.VerifyMFA(ctx, env.challenge(t, user.Email), tt.first(t, secret, recoveryCodes, at), testClientIP)
+			if !errors.Is(err, tt.wantFirst) {
+				t.Fatalf("first code: got %v, want %v", err, tt.wantFirst)
+			}
+			_, err = env.auth.VerifyMFA(ctx, env.challenge(t, user.Email), tt.second(t, secret, recoveryCodes, at), testClientIP)
+			if !errors.Is(err, tt.wantSecond) {
+				t.Errorf("second code: got %v, want %v", err, tt.wantSecond)
+			}
+		})
+	}
+}
+
+func TestMFAChallengeIsSingleUse(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	user := env.createUser(t, "challenge@example.com")
+	_, recoveryCodes := env.enrollTOTP(t, user.ID, time.Now())
+
+	mfaToken := env.challenge(t, user.Email)
+	if _, err := env.auth.VerifyMFA(ctx, mfaToken, recoveryCodes[0], testClientIP); err != nil {
+		t.Fatalf("first use of the challenge failed: %v", err)
+	}
+
+	// A fresh, valid code doesn't make a spent challenge usable again
+	if _, err := env.auth.VerifyMFA(ctx, mfaToken, recoveryCodes[1], testClientIP); !errors.Is(err, ErrInvalidMFAToken) {
+		t.Errorf("second use of the challenge: got %v, want %v", err, ErrInvalidMFAToken)
+	}
+}
+
+func TestMFAChallengeClaims(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	user := env.createUser(t, "claims@example.com")
+	_, recoveryCodes := env.enrollTOTP(t, user.ID, time.Now())
+
+	now := time.Now()
+	valid := func() mfaChallengeClaims {
+		return mfaChallengeClaims{
+			Type:   typMFAChallenge,
+			UserID: user.ID.String(),
+			RegisteredClaims: jwt.RegisteredClaims{
+				ID:        uuid.NewString(),
+				Issuer:    env.auth.issuer(),
+				Audience:  jwt.ClaimStrings{env.auth.issuer()},
+				IssuedAt:  jwt.NewNumericDate(now),
+				ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(claims *mfaChallengeClaims)
+		want   error
+	}{
+		{name: "valid", modify: func(claims *mfaChallengeClaims) {}, want: nil},
+		{name: "wrong type", modify: func(claims *mfaChallengeClaims) { claims.Type = typAccess }, want: ErrInvalidMFAToken},
+		{name: "no type", modify: func(claims *mfaChallengeClaims) { claims.Type = "" }, want: ErrInvalidMFAToken},
+		{name: "wrong issuer", modify: func(claims *mfaChallengeClaims) { claims.Issuer = "https://elsewhere.example" }, want: ErrInvalidMFAToken},
+		{name: "wrong audience", modify: func(claims *mfaChallengeClaims) { claims.Audience = jwt.ClaimStrings{"e2e-profile"} }, want: ErrInvalidMFAToken},
+		{name: "no expiry", modify: func(claims *mfaChallengeClaims) { claims.ExpiresAt = nil }, want: ErrInvalidMFAToken},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			tt.modify(&claims)
+			mfaToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(env.cfg.MFAChallengeSecret))
+			if err != nil {
+				t.Fatalf("failed to sign challenge: %v", err)
+			}
+
+			if _, err := env.auth.VerifyMFA(ctx, mfaToken, recoveryCodes[i], testClientIP); !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDisableTOTPRequiresSecondFactor(t *testing.T) {
+	tests := []struct {
+		name string
+		// code returns the code presented, given the TOTP secret, the
+		// recovery codes and the time the codes are generated for
+		code    func(t *testing.T, secret string, recoveryCodes []string, at time.Time) string
+		wantErr error
+	}{
+		{
+			name:    "missing code",
+			code:    func(t *testing.T, _ string, _ []string, _ time.Time) string { return "" },
+			wantErr: ErrInvalidMFACode,
+		},
+		{
+			name:    "wrong code",
+			code:    func(t *testing.T, _ string, _ []string, _ time.Time) string { return "000000" },
+			wantErr: ErrInvalidMFACode,
+		},
+		{
+			name: "TOTP code already used at enrollment",
+			code: func(t *testing.T, secret string, _ []string, at time.Time) string {
+				return totpCode(t, secret, at.Add(-totp.Period))
+			},
+			wantErr: ErrInvalidMFACode,
+		},
+		{
+			name: "current TOTP code",
+			code: func(t *testing.T, secret string, _ []string, at time.Time) string {
+				return totpCode(t, secret, at)
+			},
+		},
+		{
+			name: "recovery code",
+			code: func(t *testing.T, _ string, recoveryCodes []string, _ time.Time) string {
+				return recoveryCodes[0]
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			ctx := context.Background()
+			user := env.createUser(t, "disable@example.com")
+			at := time.Now()
+			secret, recoveryCodes := env.enrollTOTP(t, user.ID, at)
+
+			// Two sessions, the first one turns TOTP off
+			current, err := env.auth.VerifyMFA(ctx, env.challenge(t, user.Email), recoveryCodes[1], testClientIP)
+			if err != nil {
+				t.Fatalf("failed to sign in: %v", err)
+			}
+			other, err := env.auth.VerifyMFA(ctx, env.challenge(t, user.Email), recoveryCodes[2], testClientIP)
+			if err != nil {
+				t.Fatalf("failed to sign in: %v", err)
+			}
+			claims, err := env.auth.ValidateAccessToken(ctx, current.AccessToken)
+			if err != nil {
+				t.Fatalf("failed to validate access token: %v", err)
+			}
+
+			err = env.auth.DisableTOTP(ctx, user.ID, uuid.MustParse(claims.SessionID), testPassword, tt.code(t, secret, recoveryCodes, at), testClientIP)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+
+			stored, err := env.auth.userRepo.GetByID(ctx, user.ID)
+			if err != nil {
+				t.Fatalf("failed to load user: %v", err)
+			}
+			if stored.TOTPEnabled != (tt.wantErr != nil) {
+				t.Errorf("TOTP enabled %v after the attempt", stored.TOTPEnabled)
+			}
+
+			// Only a successful change ends the other session
+			_, err = env.auth.ValidateAccessToken(ctx, other.AccessToken)
+			if revoked := errors.Is(err, ErrTokenRevoked); revoked != (tt.wantErr == nil) {
+				t.Errorf("other session: got %v after the attempt", err)
+			}
+			if _, err := env.auth.ValidateAccessToken(ctx, current.AccessToken); err != nil {
+				t.Errorf("session making the change: got %v, want it to stay valid", err)
+			}
+		})
+	}
+}
+
+func TestDisableTOTPDropsUnconfirmedEnrollment(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	user := env.createUser(t, "pending@example.com")
+	if _, err := env.auth.EnrollTOTP(ctx, user.ID); err != nil {
+		t.Fatalf("failed to enroll TOTP: %v", err)
+	}
+
+	if err := env.auth.DisableTOTP(ctx, user.ID, uuid.New(), testPassword, "", testClientIP); err != nil {
+		t.Fatalf("DisableTOTP failed: %v", err)
+	}
+	if err := env.auth.DisableTOTP(ctx, user.ID, uuid.New(), testPassword, "", testClientIP); !errors.Is(err, ErrTOTPNotEnrolled) {
+		t.Errorf("second DisableTOTP: got %v, want %v", err, ErrTOTPNotEnrolled)
+	}
+}
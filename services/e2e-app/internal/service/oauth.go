@@ -0,0 +1,452 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/tanerincode/e2e-app/internal/metrics"
+	"github.com/tanerincode/e2e-app/internal/model"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Scopes understood by the OpenID Connect endpoints
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+// OAuth grant types supported by the token endpoint
+const (
+	grantAuthorizationCode = "authorization_code"
+	grantRefreshToken      = "refresh_token"
+)
+
+// ErrInvalidAuthorizationTarget is returned when an authorization request
+// names an unknown client or a redirect URI that isn't registered for it.
+// Such errors must be shown to the user instead of being redirected.
+var ErrInvalidAuthorizationTarget = errors.New("unknown client or unregistered redirect URI")
+
+// OAuthError is an OAuth 2.0 error response (RFC 6749 sections 4.1.2.1 and
+// 5.2). Code is one of the error codes defined there.
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func newOAuthError(code, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+// idTokenClaims are the claims carried by an OpenID Connect ID token
+type idTokenClaims struct {
+	Nonce         string           `json:"nonce,omitempty"`
+	AuthTime      *jwt.NumericDate `json:"auth_time,omitempty"`
+	AMR           []string         `json:"amr,omitempty"`
+	Email         string           `json:"email,omitempty"`
+	EmailVerified *bool            `json:"email_verified,omitempty"`
+	Name          string           `json:"name,omitempty"`
+	GivenName     string           `json:"given_name,omitempty"`
+	FamilyName    string           `json:"family_name,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// NewOAuthClient creates a client registration with a random client ID. For
+// confidential clients it also returns the client secret, which is only
+// stored hashed and can't be shown again.
+func NewOAuthClient(name string, redirectURIs []string, public bool) (*model.OAuthClient, string, error) {
+	if name == "" {
+		return nil, "", errors.New("client name is required")
+	}
+	if len(redirectURIs) == 0 {
+		return nil, "", errors.New("at least one redirect URI is required")
+	}
+	for _, uri := range redirectURIs {
+		parsed, err := url.Parse(uri)
+		if err != nil || !parsed.IsAbs() || parsed.Fragment != "" {
+			return nil, "", fmt.Errorf("redirect URI %q must be an absolute URL without a fragment", uri)
+		}
+	}
+
+	id, err := randomString(16)
+	if err != nil {
+		return nil, "", err
+	}
+
+	client := &model.OAuthClient{
+		ID:           id,
+		Name:         name,
+		RedirectURIs: strings.Join(redirectURIs, "\n"),
+		Public:       public,
+	}
+	if public {
+		return client, "", nil
+	}
+
+	secret, err := randomString(32)
+	if err != nil {
+		return nil, "", err
+	}
+	hashedSecret, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, "", err
+	}
+	client.SecretHash = string(hashedSecret)
+
+	return client, secret, nil
+}
+
+// OpenIDConfiguration returns the discovery document
+func (s *authService) OpenIDConfiguration() *model.OpenIDConfiguration {
+	issuer := s.issuer()
+	return &model.OpenIDConfiguration{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/oauth/authorize",
+		TokenEndpoint:                     issuer + "/oauth/token",
+		IntrospectionEndpoint:             issuer + "/oauth/introspect",
+		UserInfoEndpoint:                  issuer + "/userinfo",
+		JWKSURI:                           issuer + "/.well-known/jwks.json",
+		ScopesSupported:                   []string{ScopeOpenID, ScopeProfile, ScopeEmail},
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{grantAuthorizationCode, grantRefreshToken},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{s.keys.Algorithm()},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported: []string{
+			"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "amr",
+			"email", "email_verified", "name", "given_name", "family_name",
+		},
+	}
+}
+
+// ValidateAuthorizationRequest checks an authorization request before the
+// user is asked to sign in. ErrInvalidAuthorizationTarget means the client
+// or redirect URI can't be trusted; any other problem is an *OAuthError that
+// should be reported back to the redirect URI.
+func (s *authService) ValidateAuthorizationRequest(ctx context.Context, req *model.AuthorizationRequest) (*model.OAuthClient, error) {
+	client, err := s.oauthClientRepo.GetByID(ctx, req.ClientID)
+	if err != nil || !client.AllowsRedirectURI(req.RedirectURI) {
+		return nil, ErrInvalidAuthorizationTarget
+	}
+
+	if !s.keys.Asymmetric() {
+		return nil, newOAuthError("server_error", "OpenID Connect needs an asymmetric signing key")
+	}
+	if req.ResponseType != "code" {
+		return nil, newOAuthError("unsupported_response_type", "only the authorization code flow is supported")
+	}
+	for _, scope := range strings.Fields(req.Scope) {
+		if scope != ScopeOpenID && scope != ScopeProfile && scope != ScopeEmail {
+			return nil, newOAuthError("invalid_scope", fmt.Sprintf("unsupported scope %q", scope))
+		}
+	}
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != "S256" {
+		return nil, newOAuthError("invalid_request", "PKCE with code_challenge_method S256 is required")
+	}
+	if len(req.CodeChallenge) < 43 || len(req.CodeChallenge) > 128 {
+		return nil, newOAuthError("invalid_request", "code_challenge has an invalid length")
+	}
+
+	return client, nil
+}
+
+// Authorize signs the user in for an authorization request and returns the
+// authorization code to send back to the client. Accounts with two-factor
+// authentication first get an MFA challenge; the credentials of the second
+// call then carry the challenge token and code instead of the password.
+func (s *authService) Authorize(ctx context.Context, req *model.AuthorizationRequest, creds *model.AuthorizationCredentials, clientIP string) (string, *model.MFAChallenge, error) {
+	if _, err := s.ValidateAuthorizationRequest(ctx, req); err != nil {
+		return "", nil, err
+	}
+
+	var user *model.User
+	var amr []string
+	if creds.MFAToken != "" {
+		var err error
+		user, amr, err = s.completeMFA(ctx, creds.MFAToken, creds.Code, clientIP)
+		metrics.RecordLogin(metrics.LoginMFA, false, err)
+		if err != nil {
+			return "", nil, err
+		}
+	} else {
+		var challenge *model.MFAChallenge
+		var err error
+		user, challenge, err = s.authenticate(ctx, creds.Email, creds.Password, clientIP)
+		metrics.RecordLogin(metrics.LoginPassword, challenge != nil, err)
+		if err != nil {
+			return "", nil, err
+		}
+		if challenge != nil {
+			return "", challenge, nil
+		}
+		amr = []string{amrPassword}
+	}
+
+	code, err := randomString(32)
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	stored := &model.AuthorizationCode{
+		CodeHash:      hashAuthorizationCode(code),
+		ClientID:      req.ClientID,
+		UserID:        user.ID,
+		FamilyID:      uuid.New(),
+		RedirectURI:   req.RedirectURI,
+		Scope:         req.Scope,
+		Nonce:         req.Nonce,
+		CodeChallenge: req.CodeChallenge,
+		AMR:           strings.Join(amr, " "),
+		AuthTime:      now,
+		ExpiresAt:     now.Add(s.config.GetOAuthCodeExpiration()),
+	}
+	if err := s.authorizationCodeRepo.Create(ctx, stored); err != nil {
+		return "", nil, err
+	}
+
+	return code, nil, nil
+}
+
+// ExchangeToken implements the token endpoint for the authorization code and
+// refresh token grants. Failures are reported as *OAuthError.
+func (s *authService) ExchangeToken(ctx context.Context, req *model.OAuthTokenRequest) (*model.TokenResponse, error) {
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.GrantType {
+	case grantAuthorizationCode:
+		return s.exchangeAuthorizationCode(ctx, client, req)
+	case grantRefreshToken:
+		claims, err := s.parseRefreshToken(req.RefreshToken)
+		if err != nil || claims.ClientID != client.ID {
+			metrics.RecordRefresh(metrics.ResultFailure)
+			return nil, newOAuthError("invalid_grant", "invalid refresh token")
+		}
+		tokens, err := s.rotateRefreshToken(ctx, claims)
+		metrics.RecordRefresh(refreshResult(err))
+		if errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrRefreshTokenRevoked) || errors.Is(err, ErrRefreshTokenReused) || errors.Is(err, ErrAccountDisabled) {
+			return nil, newOAuthError("invalid_grant", err.Error())
+		}
+		return tokens, err
+	default:
+		return nil, newOAuthError("unsupported_grant_type", fmt.Sprintf("grant type %q is not supported", req.GrantType))
+	}
+}
+
+// ValidateUserInfoToken verifies an access token sent to /userinfo. It takes
+// the tokens issued to OAuth clients, which are only good there, as well as
+// first-party tokens.
+func (s *authService) ValidateUserInfoToken(ctx context.Context, accessToken string) (*AccessClaims, error) {
+	claims, err := s.ValidateAccessTokenFor(ctx, accessToken, s.userInfoAudience())
+	if errors.Is(err, ErrInvalidToken) {
+		return s.ValidateAccessToken(ctx, accessToken)
+	}
+	return claims, err
+}
+
+// UserInfo returns the claims about the token's user that its scope allows.
+// First-party tokens from /auth/login aren't limited by scope.
+func (s *authService) UserInfo(ctx context.Context, claims *AccessClaims) (*model.UserInfo, error) {
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := claims.Scope
+	if claims.ClientID == "" {
+		scope = strings.Join([]string{ScopeOpenID, ScopeProfile, ScopeEmail}, " ")
+	}
+	return userInfo(user, scope), nil
+}
+
+// authenticateClient checks the client credentials sent to the token
+// endpoint. Public clients authenticate with their client ID alone.
+func (s *authService) authenticateClient(ctx context.Context, clientID, clientSecret string) (*model.OAuthClient, error) {
+	client, err := s.oauthClientRepo.GetByID(ctx, clientID)
+	if err != nil {
+		return nil, newOAuthError("invalid_client", "unknown client")
+	}
+
+	if client.Public {
+		if clientSecret != "" {
+			return nil, newOAuthError("invalid_client", "public clients have no secret")
+		}
+		return client, nil
+	}
+
+	if clientSecret == "" || bcrypt.CompareHashAndPassword([]byte(client.SecretHash), []byte(clientSecret)) != nil {
+		return nil, newOAuthError("invalid_client", "invalid client credentials")
+	}
+	return client, nil
+}
+
+// exchangeAuthorizationCode redeems an authorization code. A code that is
+// presented a second time revokes the session it was exchanged for, since one
+// of the two parties holding it shouldn't.
+func (s *authService) exchangeAuthorizationCode(ctx context.Context, client *model.OAuthClient, req *model.OAuthTokenRequest) (*model.TokenResponse, error) {
+	invalidGrant := newOAuthError("invalid_grant", "invalid authorization code")
+
+	stored, err := s.authorizationCodeRepo.GetByHash(ctx, hashAuthorizationCode(req.Code))
+	if err != nil || stored.ClientID != client.ID {
+		return nil, invalidGrant
+	}
+	if stored.UsedAt != nil {
+		if err := s.refreshTokenRepo.RevokeFamily(ctx, stored.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, invalidGrant
+	}
+	if time.Now().After(stored.ExpiresAt) || stored.RedirectURI != req.RedirectURI {
+		return nil, invalidGrant
+	}
+	if !verifyCodeChallenge(stored.CodeChallenge, req.CodeVerifier) {
+		return nil, newOAuthError("invalid_grant", "code_verifier does not match the code challenge")
+	}
+
+	used, err := s.authorizationCodeRepo.MarkUsed(ctx, stored.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		if err := s.refreshTokenRepo.RevokeFamily(ctx, stored.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, invalidGrant
+	}
+
+	user, err := s.userRepo.GetByID(ctx, stored.UserID)
+	if err != nil || user.Disabled {
+		return nil, invalidGrant
+	}
+
+	amr := strings.Fields(stored.AMR)
+	tokens, err := s.generateTokens(ctx, user, session{
+		familyID: stored.FamilyID,
+		amr:      amr,
+		clientID: client.ID,
+		scope:    stored.Scope,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if hasScope(stored.Scope, ScopeOpenID) {
+		tokens.IDToken, err = s.issueIDToken(user, client.ID, stored, amr)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tokens, nil
+}
+
+// issueIDToken signs an ID token for a redeemed authorization code
+func (s *authService) issueIDToken(user *model.User, clientID string, code *model.AuthorizationCode, amr []string) (string, error) {
+	if !s.keys.Asymmetric() {
+		return "", newOAuthError("server_error", "OpenID Connect needs an asymmetric signing key")
+	}
+
+	now := time.Now()
+	info := userInfo(user, code.Scope)
+
+	return s.keys.Sign(idTokenClaims{
+		Nonce:         code.Nonce,
+		AuthTime:      jwt.NewNumericDate(code.AuthTime),
+		AMR:           amr,
+		Email:         info.Email,
+		EmailVerified: info.EmailVerified,
+		Name:          info.Name,
+		GivenName:     info.GivenName,
+		FamilyName:    info.FamilyName,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.issuer(),
+			Subject:   user.ID.String(),
+			Audience:  jwt.ClaimStrings{clientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.config.GetJWTExpiration())),
+		},
+	})
+}
+
+// userInfoAudience is the audience of access tokens issued to OAuth clients
+func (s *authService) userInfoAudience() string {
+	return s.issuer() + "/userinfo"
+}
+
+// issuer returns the OpenID Connect issuer identifier
+func (s *authService) issuer() string {
+	return strings.TrimRight(s.config.PublicURL, "/")
+}
+
+// userInfo returns the claims about a user that scope allows
+func userInfo(user *model.User, scope string) *model.UserInfo {
+	info := &model.UserInfo{Subject: user.ID.String()}
+	if hasScope(scope, ScopeEmail) {
+		verified := user.EmailVerified
+		info.Email = user.Email
+		info.EmailVerified = &verified
+	}
+	if hasScope(scope, ScopeProfile) {
+		info.Name = strings.TrimSpace(user.FirstName + " " + user.LastName)
+		info.GivenName = user.FirstName
+		info.FamilyName = user.LastName
+	}
+	return info
+}
+
+func hasScope(scope, want string) bool {
+	for _, s := range strings.Fields(scope) {
+		if s == want {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyCodeChallenge checks a PKCE code verifier against an S256 challenge
+func verifyCodeChallenge(challenge, verifier string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+// randomString returns n random bytes encoded as unpadded base64url
+func randomString(n int) (string, error) {
+	raw := make([]byte, n)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// hashAuthorizationCode returns the hex encoded SHA-256 hash of an authorization code
+func hashAuthorizationCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
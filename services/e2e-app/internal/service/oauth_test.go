@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tanerincode/e2e-app/internal/config"
+	"github.com/tanerincode/e2e-app/internal/model"
+	"github.com/tanerincode/e2e-app/internal/rbac"
+	"github.com/tanerincode/e2e-app/internal/repository"
+)
+
+const testRedirectURI = "https://client.example/callback"
+
+// testCodeVerifier is the PKCE code verifier authorize sends its challenge for
+var testCodeVerifier = strings.Repeat("v", 43)
+
+// registerClient registers a public OAuth client redirecting to testRedirectURI
+func (e *testEnv) registerClient(t *testing.T) *model.OAuthClient {
+	t.Helper()
+
+	client, _, err := NewOAuthClient("Test client", []string{testRedirectURI}, true)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	if err := repository.NewOAuthClientRepository(e.db).Create(context.Background(), client); err != nil {
+		t.Fatalf("failed to register client: %v", err)
+	}
+	return client
+}
+
+// authorize signs a user in for client and returns the authorization code,
+// issued for the S256 challenge of testCodeVerifier
+func (e *testEnv) authorize(t *testing.T, client *model.OAuthClient, email string) string {
+	t.Helper()
+
+	sum := sha256.Sum256([]byte(testCodeVerifier))
+	req := &model.AuthorizationRequest{
+		ResponseType:        "code",
+		ClientID:            client.ID,
+		RedirectURI:         testRedirectURI,
+		Scope:               ScopeOpenID,
+		CodeChallenge:       base64.RawURLEncoding.EncodeToString(sum[:]),
+		CodeChallengeMethod: "S256",
+	}
+	creds := &model.AuthorizationCredentials{Email: email, Password: testPassword}
+	code, challenge, err := e.auth.Authorize(context.Background(), req, creds, testClientIP)
+	if err != nil {
+		t.Fatalf("failed to authorize %s: %v", email, err)
+	}
+	if challenge != nil {
+		t.Fatalf("authorization of %s asked for a second factor", email)
+	}
+	return code
+}
+
+// exchangeCode redeems an authorization code at the token endpoint
+func (e *testEnv) exchangeCode(client *model.OAuthClient, code, verifier string) (*model.TokenResponse, error) {
+	return e.auth.ExchangeToken(context.Background(), &model.OAuthTokenRequest{
+		GrantType:    grantAuthorizationCode,
+		Code:         code,
+		RedirectURI:  testRedirectURI,
+		CodeVerifier: verifier,
+		ClientID:     client.ID,
+	})
+}
+
+// withSigningKey signs tokens with a fresh Ed25519 key, which OpenID Connect
+// needs
+func withSigningKey(t *testing.T) func(cfg *config.Config) {
+	path := pkcs8File(t, "signing.pem", newEd25519Key(t))
+	return func(cfg *config.Config) {
+		cfg.JWTSigningKeyFile = path
+	}
+}
+
+// oauthErrorCode returns the OAuth error code of err, or "" if it isn't one
+func oauthErrorCode(err error) string {
+	var oauthErr *OAuthError
+	if errors.As(err, &oauthErr) {
+		return oauthErr.Code
+	}
+	return ""
+}
+
+func TestAuthorizationCodePKCE(t *testing.T) {
+	tests := []struct {
+		name     string
+		verifier string
+		wantErr  string
+	}{
+		{name: "matching verifier", verifier: testCodeVerifier, wantErr: ""},
+		{name: "other verifier", verifier: strings.Repeat("w", 43), wantErr: "invalid_grant"},
+		{name: "challenge sent as the verifier", verifier: func() string {
+			sum := sha256.Sum256([]byte(testCodeVerifier))
+			return base64.RawURLEncoding.EncodeToString(sum[:])
+		}(), wantErr: "invalid_grant"},
+		{name: "no verifier", verifier: "", wantErr: "invalid_grant"},
+		{name: "verifier too short", verifier: testCodeVerifier[:42], wantErr: "invalid_grant"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, withSigningKey(t))
+			client := env.registerClient(t)
+			env.createUser(t, "pkce@example.com")
+			code := env.authorize(t, client, "pkce@example.com")
+
+			tokens, err := env.exchangeCode(client, code, tt.verifier)
+			if got := oauthErrorCode(err); got != tt.wantErr || (tt.wantErr == "" && err != nil) {
+				t.Fatalf("got %v, want error code %q", err, tt.wantErr)
+			}
+			if tt.wantErr == "" && tokens.IDToken == "" {
+				t.Errorf("openid scope didn't return an ID token")
+			}
+		})
+	}
+}
+
+func TestAuthorizationCodeReuseRevokesSession(t *testing.T) {
+	env := newTestEnv(t, withSigningKey(t))
+	ctx := context.Background()
+	client := env.registerClient(t)
+	env.createUser(t, "code@example.com")
+	code := env.authorize(t, client, "code@example.com")
+
+	tokens, err := env.exchangeCode(client, code, testCodeVerifier)
+	if err != nil {
+		t.Fatalf("first exchange failed: %v", err)
+	}
+	if _, err := env.exchangeCode(client, code, testCodeVerifier); oauthErrorCode(err) != "invalid_grant" {
+		t.Fatalf("second exchange: got %v, want invalid_grant", err)
+	}
+
+	// Both parties that held the code lose the session it was exchanged for
+	if _, err := env.auth.ValidateUserInfoToken(ctx, tokens.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("access token of the first exchange: got %v, want %v", err, ErrTokenRevoked)
+	}
+	_, err = env.auth.ExchangeToken(ctx, &model.OAuthTokenRequest{
+		GrantType:    grantRefreshToken,
+		RefreshToken: tokens.RefreshToken,
+		ClientID:     client.ID,
+	})
+	if oauthErrorCode(err) != "invalid_grant" || !strings.Contains(err.Error(), ErrRefreshTokenRevoked.Error()) {
+		t.Errorf("refresh token of the first exchange: got %v, want %v", err, ErrRefreshTokenRevoked)
+	}
+}
+
+func TestOAuthRefreshTokenStaysWithClient(t *testing.T) {
+	env := newTestEnv(t, withSigningKey(t))
+	ctx := context.Background()
+	client := env.registerClient(t)
+	env.createUser(t, "client@example.com")
+	tokens, err := env.exchangeCode(client, env.authorize(t, client, "client@example.com"), testCodeVerifier)
+	if err != nil {
+		t.Fatalf("failed to exchange code: %v", err)
+	}
+
+	// The first-party endpoint would drop the client's scope
+	if _, err := env.auth.RefreshToken(ctx, tokens.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("first-party refresh of a client's token: got %v, want %v", err, ErrInvalidRefreshToken)
+	}
+
+	// The rejection doesn't rotate the token, so the client can still use it
+	if _, err := env.auth.ExchangeToken(ctx, &model.OAuthTokenRequest{
+		GrantType:    grantRefreshToken,
+		RefreshToken: tokens.RefreshToken,
+		ClientID:     client.ID,
+	}); err != nil {
+		t.Errorf("refresh at the token endpoint failed: %v", err)
+	}
+}
+
+func TestOpenIDConnectNeedsAsymmetricKey(t *testing.T) {
+	env := newTestEnv(t)
+	client := env.registerClient(t)
+
+	sum := sha256.Sum256([]byte(testCodeVerifier))
+	_, err := env.auth.ValidateAuthorizationRequest(context.Background(), &model.AuthorizationRequest{
+		ResponseType:        "code",
+		ClientID:            client.ID,
+		RedirectURI:         testRedirectURI,
+		Scope:               ScopeOpenID,
+		CodeChallenge:       base64.RawURLEncoding.EncodeToString(sum[:]),
+		CodeChallengeMethod: "S256",
+	})
+	if oauthErrorCode(err) != "server_error" {
+		t.Fatalf("authorization with an HS256 key set: got %v, want server_error", err)
+	}
+}
+
+func TestClientTokensOnlyOpenUserInfo(t *testing.T) {
+	env := newTestEnv(t, withSigningKey(t))
+	ctx := context.Background()
+	client := env.registerClient(t)
+	user := env.createUser(t, "admin@example.com")
+	user.GrantRole(rbac.RoleAdmin)
+	if err := repository.NewUserRepository(env.db).UpdateAccess(ctx, user.ID, user.Roles, user.Permissions); err != nil {
+		t.Fatalf("failed to grant admin: %v", err)
+	}
+
+	tokens, err := env.exchangeCode(client, env.authorize(t, client, "admin@example.com"), testCodeVerifier)
+	if err != nil {
+		t.Fatalf("failed to exchange code: %v", err)
+	}
+
+	claims, err := env.auth.ValidateUserInfoToken(ctx, tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("userinfo rejected the client's token: %v", err)
+	}
+	if claims.ClientID != client.ID {
+		t.Errorf("got client_id %q, want %q", claims.ClientID, client.ID)
+	}
+	if len(claims.Roles) != 0 || len(claims.Permissions) != 0 {
+		t.Errorf("client token carries roles %v and permissions %v, want none", claims.Roles, claims.Permissions)
+	}
+
+	// Neither this service nor the ones it issues tokens for take it
+	for _, audience := range env.cfg.GetJWTAudience() {
+		if _, err := env.auth.ValidateAccessTokenFor(ctx, tokens.AccessToken, audience); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("audience %s: got %v, want %v", audience, err, ErrInvalidToken)
+		}
+	}
+
+	// First-party tokens still work at /userinfo
+	if _, err := env.auth.ValidateUserInfoToken(ctx, env.login(t, "admin@example.com").AccessToken); err != nil {
+		t.Errorf("userinfo rejected a first-party token: %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tanerincode/e2e-app/internal/mail"
+	"github.com/tanerincode/e2e-app/internal/model"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidResetToken is returned when a password reset token is unknown,
+// expired or has already been used
+var ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+
+// ForgotPassword starts a password reset. The outcome is the same whether or
+// not the email belongs to an account, and the email is sent in the
+// background so response times don't give it away either.
+func (s *authService) ForgotPassword(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return nil
+	}
+
+	go func() {
+		if err := s.sendPasswordReset(context.WithoutCancel(ctx), user); err != nil {
+			log.Printf("Failed to send password reset email to %s: %v", user.Email, err)
+		}
+	}()
+	return nil
+}
+
+// ResetPassword sets a new password using a reset token and revokes every
+// existing session of the user
+func (s *authService) ResetPassword(ctx context.Context, resetToken, newPassword string) error {
+	stored, err := s.passwordResetRepo.GetByHash(ctx, hashResetToken(resetToken))
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+	if stored.UsedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+
+	used, err := s.passwordResetRepo.MarkUsed(ctx, stored.ID)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidResetToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, stored.UserID)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdatePassword(ctx, user.ID, string(hashedPassword)); err != nil {
+		return err
+	}
+
+	// The reset link was delivered to the address, which proves ownership
+	if !user.EmailVerified {
+		if err := s.userRepo.MarkEmailVerified(ctx, user.ID, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	// Any other outstanding reset links are no longer needed
+	if err := s.passwordResetRepo.InvalidateByUserID(ctx, user.ID); err != nil {
+		return err
+	}
+
+	return s.refreshTokenRepo.RevokeByUserID(ctx, user.ID)
+}
+
+// ForcePasswordReset makes a user choose a new password. The current password
+// stops working right away, every session is revoked and the user is mailed
+// a reset link. Unlike ForgotPassword the email is sent before returning, so
+// the administrator learns about delivery failures.
+func (s *authService) ForcePasswordReset(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	placeholder, err := randomString(32)
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(placeholder), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdatePassword(ctx, user.ID, string(hashedPassword)); err != nil {
+		return err
+	}
+	if err := s.refreshTokenRepo.RevokeByUserID(ctx, user.ID); err != nil {
+		return err
+	}
+
+	return s.sendPasswordReset(ctx, user)
+}
+
+// sendPasswordReset stores a new reset token and mails it to the user
+func (s *authService) sendPasswordReset(ctx context.Context, user *model.User) error {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	resetToken := base64.RawURLEncoding.EncodeToString(raw)
+
+	stored := &model.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashResetToken(resetToken),
+		ExpiresAt: time.Now().Add(s.config.GetPasswordResetExpiration()),
+	}
+	if err := s.passwordResetRepo.Create(ctx, stored); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s?token=%s", s.config.PasswordResetURL, url.QueryEscape(resetToken))
+
+	return s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your account. If it was you, open the link below to choose a new one:\n\n%s\n\nThe link expires in %s and can only be used once. If you didn't ask for this, you can ignore this email.",
+			user.FirstName, link, s.config.GetPasswordResetExpiration()),
+	})
+}
+
+// hashResetToken returns the hex encoded SHA-256 hash of a reset token
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
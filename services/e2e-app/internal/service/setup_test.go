@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tanerincode/e2e-app/internal/config"
+	"github.com/tanerincode/e2e-app/internal/federation"
+	"github.com/tanerincode/e2e-app/internal/mail"
+	"github.com/tanerincode/e2e-app/internal/model"
+	"github.com/tanerincode/e2e-app/internal/repository"
+	"github.com/tanerincode/e2e-app/internal/throttle"
+	"gorm.io/gorm"
+)
+
+// testPassword is the password of every user created by testEnv.createUser
+const testPassword = "correct horse battery staple"
+
+// testClientIP is the client IP requests in tests come from
+const testClientIP = "192.0.2.1"
+
+// testEnv wires the services to a fresh mock database
+type testEnv struct {
+	cfg   *config.Config
+	db    *gorm.DB
+	auth  *authService
+	users *UserService
+}
+
+// newTestEnv creates the services on a fresh mock database. configure may
+// adjust the configuration before anything is built from it. The database is
+// dropped when the test ends.
+func newTestEnv(t *testing.T, configure ...func(cfg *config.Config)) *testEnv {
+	t.Helper()
+
+	cfg := config.New()
+	cfg.AppEnv = "development"
+	cfg.MockDB = true
+	cfg.LoginThrottleStore = "memory"
+	for _, fn := range configure {
+		fn(cfg)
+	}
+
+	db, err := repository.NewDB(cfg)
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	// Closing the last connection drops the in-memory database, so the next
+	// test starts from an empty one
+	t.Cleanup(func() {
+		if err := repository.CloseDB(db); err != nil {
+			t.Errorf("failed to close mock database: %v", err)
+		}
+	})
+
+	keys, err := NewKeySet(cfg)
+	if err != nil {
+		t.Fatalf("failed to load keys: %v", err)
+	}
+	limiter, err := throttle.New(cfg, db)
+	if err != nil {
+		t.Fatalf("failed to create login limiter: %v", err)
+	}
+	registry, err := federation.New(cfg)
+	if err != nil {
+		t.Fatalf("failed to create federation registry: %v", err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	auth := NewAuthService(
+		userRepo,
+		refreshTokenRepo,
+		repository.NewRevokedTokenRepository(db),
+		repository.NewPasswordResetTokenRepository(db),
+		repository.NewRecoveryCodeRepository(db),
+		repository.NewOAuthClientRepository(db),
+		repository.NewAuthorizationCodeRepository(db),
+		repository.NewLinkedIdentityRepository(db),
+		keys,
+		mail.NewLogSender(),
+		limiter,
+		registry,
+		cfg,
+	)
+
+	return &testEnv{
+		cfg:   cfg,
+		db:    db,
+		auth:  auth.(*authService),
+		users: NewUserService(userRepo, refreshTokenRepo, limiter),
+	}
+}
+
+// createUser registers a user with testPassword
+func (e *testEnv) createUser(t *testing.T, email string) *model.User {
+	t.Helper()
+
+	user := &model.User{
+		Email:     email,
+		Password:  testPassword,
+		FirstName: "Test",
+		LastName:  "User",
+	}
+	if err := e.auth.Register(context.Background(), user); err != nil {
+		t.Fatalf("failed to register %s: %v", email, err)
+	}
+	return user
+}
+
+// login signs a user without two-factor authentication in
+func (e *testEnv) login(t *testing.T, email string) *model.TokenResponse {
+	t.Helper()
+
+	result, err := e.auth.Login(context.Background(), email, testPassword, testClientIP)
+	if err != nil {
+		t.Fatalf("failed to log %s in: %v", email, err)
+	}
+	if result.Tokens == nil {
+		t.Fatalf("login of %s returned no tokens", email)
+	}
+	return result.Tokens
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/tanerincode/e2e-app/internal/model"
+	"github.com/tanerincode/e2e-app/internal/throttle"
+)
+
+// loginThrottle counts failed password and second factor checks towards the
+// account and client IP lockouts. Both services embed it, so a password
+// re-checked before a sensitive change shares the lockouts of the login form.
+type loginThrottle struct {
+	limiter *throttle.Limiter
+}
+
+// checkLoginThrottle returns a *throttle.LockedError while the account or
+// client IP is locked out. The throttle store being unavailable shouldn't
+// lock everyone out, so any other error is only logged.
+func (t *loginThrottle) checkLoginThrottle(ctx context.Context, email, clientIP string) error {
+	err := t.limiter.Check(ctx, email, clientIP)
+	var locked *throttle.LockedError
+	if errors.As(err, &locked) {
+		return err
+	}
+	if err != nil {
+		log.Printf("Failed to check login throttle for %s: %v", email, err)
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed login towards the account and IP lockouts
+func (t *loginThrottle) recordLoginFailure(ctx context.Context, email, clientIP string) {
+	if err := t.limiter.Fail(ctx, email, clientIP); err != nil {
+		log.Printf("Failed to record failed login for %s: %v", email, err)
+	}
+}
+
+// clearLoginFailures forgets the failed logins of an account after it signed in
+func (t *loginThrottle) clearLoginFailures(ctx context.Context, email string) {
+	if err := t.limiter.Succeed(ctx, email); err != nil {
+		log.Printf("Failed to reset login throttle for %s: %v", email, err)
+	}
+}
+
+// recheckPassword checks the password of a signed in user before a sensitive
+// change. Wrong passwords count as failed logins, so a stolen session can't
+// be used to guess the password without running into the lockout.
+func (t *loginThrottle) recheckPassword(ctx context.Context, user *model.User, password, clientIP string) error {
+	if err := t.checkLoginThrottle(ctx, user.Email, clientIP); err != nil {
+		return err
+	}
+	if err := comparePasswords(user.Password, password); err != nil {
+		t.recordLoginFailure(ctx, user.Email, clientIP)
+		return ErrInvalidCredentials
+	}
+	t.clearLoginFailures(ctx, user.Email)
+	return nil
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/tanerincode/e2e-app/internal/model"
+	"github.com/tanerincode/e2e-app/internal/throttle"
+)
+
+func TestPasswordRechecksShareLoginLockout(t *testing.T) {
+	tests := []struct {
+		name  string
+		check func(env *testEnv, user *model.User, password string) error
+	}{
+		{
+			name: "login",
+			check: func(env *testEnv, user *model.User, password string) error {
+				_, err := env.auth.Login(context.Background(), user.Email, password, testClientIP)
+				return err
+			},
+		},
+		{
+			name: "change password",
+			check: func(env *testEnv, user *model.User, password string) error {
+				return env.users.ChangePassword(context.Background(), user.ID, uuid.New(), password, "a new password", testClientIP)
+			},
+		},
+		{
+			name: "delete account",
+			check: func(env *testEnv, user *model.User, password string) error {
+				return env.users.DeleteAccount(context.Background(), user.ID, password, testClientIP)
+			},
+		},
+		{
+			name: "disable TOTP",
+			check: func(env *testEnv, user *model.User, password string) error {
+				return env.auth.DisableTOTP(context.Background(), user.ID, uuid.New(), password, "", testClientIP)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+" locks out at the threshold", func(t *testing.T) {
+			env := newTestEnv(t)
+			user := env.createUser(t, "locked@example.com")
+
+			for i := 0; i < env.cfg.LoginMaxAccountFailures; i++ {
+				err := tt.check(env, user, "wrong password")
+				var locked *throttle.LockedError
+				if err == nil || errors.As(err, &locked) {
+					t.Fatalf("attempt %d with a wrong password: got %v, want a rejection", i+1, err)
+				}
+			}
+
+			// The right password no longer helps, neither here nor at login
+			var locked *throttle.LockedError
+			if err := tt.check(env, user, testPassword); !errors.As(err, &locked) {
+				t.Errorf("right password after the threshold: got %v, want a lockout", err)
+			}
+			if _, err := env.auth.Login(context.Background(), user.Email, testPassword, "198.51.100.1"); !errors.As(err, &locked) {
+				t.Errorf("login from another IP after the threshold: got %v, want a lockout", err)
+			}
+		})
+
+		t.Run(tt.name+" failures reset on login", func(t *testing.T) {
+			env := newTestEnv(t)
+			user := env.createUser(t, "reset@example.com")
+
+			for round := 0; round < 2; round++ {
+				for i := 0; i < env.cfg.LoginMaxAccountFailures-1; i++ {
+					if err := tt.check(env, user, "wrong password"); err == nil {
+						t.Fatalf("attempt with a wrong password succeeded")
+					}
+				}
+				if _, err := env.auth.Login(context.Background(), user.Email, testPassword, testClientIP); err != nil {
+					t.Fatalf("login below the threshold in round %d: %v", round+1, err)
+				}
+			}
+		})
+	}
+}
@@ -7,18 +7,27 @@ import (
 	"github.com/google/uuid"
 	"github.com/tanerincode/e2e-app/internal/model"
 	"github.com/tanerincode/e2e-app/internal/repository"
+	"github.com/tanerincode/e2e-app/internal/throttle"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrInvalidCredentials is returned when a password re-check fails
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
 // UserService handles business logic for user-related operations
 type UserService struct {
-	userRepo repository.UserRepository
+	userRepo         repository.UserRepository
+	refreshTokenRepo repository.RefreshTokenRepository
+	loginThrottle
 }
 
-// NewUserService creates a new instance of UserService
-func NewUserService(userRepo repository.UserRepository) *UserService {
+// NewUserService creates a new instance of UserService. Password re-checks
+// count towards the login lockouts kept by limiter.
+func NewUserService(userRepo repository.UserRepository, refreshTokenRepo repository.RefreshTokenRepository, limiter *throttle.Limiter) *UserService {
 	return &UserService{
-		userRepo: userRepo,
+		userRepo:         userRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		loginThrottle:    loginThrottle{limiter: limiter},
 	}
 }
 
@@ -43,6 +52,11 @@ func (s *UserService) GetUserByID(ctx context.Context, id uuid.UUID) (*model.Use
 	return s.userRepo.GetByID(ctx, id)
 }
 
+// GetUsersByIDs retrieves the users with the given IDs, skipping IDs without a user
+func (s *UserService) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]*model.User, error) {
+	return s.userRepo.GetByIDs(ctx, ids)
+}
+
 // GetUserByEmail retrieves a user by their email
 func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
 	return s.userRepo.GetByEmail(ctx, email)
@@ -56,18 +70,74 @@ func (s *UserService) AuthenticateUser(ctx context.Context, email, password stri
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, ErrInvalidCredentials
 	}
 
 	return user, nil
 }
 
-// UpdateUser updates user information
-func (s *UserService) UpdateUser(ctx context.Context, user *model.User) error {
-	return s.userRepo.Update(ctx, user)
-}
-
 // DeleteUser deletes a user by their ID
 func (s *UserService) DeleteUser(ctx context.Context, id uuid.UUID) error {
 	return s.userRepo.Delete(ctx, id)
 }
+
+// UpdateProfile updates the name fields present in the request
+func (s *UserService) UpdateProfile(ctx context.Context, id uuid.UUID, req *model.UpdateProfileRequest) (*model.User, error) {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.FirstName != nil {
+		user.FirstName = *req.FirstName
+	}
+	if req.LastName != nil {
+		user.LastName = *req.LastName
+	}
+
+	if err := s.userRepo.UpdateName(ctx, user.ID, user.FirstName, user.LastName); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ChangePassword replaces the password after checking the current one and
+// revokes every session except the one making the change
+func (s *UserService) ChangePassword(ctx context.Context, id, sessionID uuid.UUID, currentPassword, newPassword, clientIP string) error {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.recheckPassword(ctx, user, currentPassword, clientIP); err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdatePassword(ctx, user.ID, string(hashedPassword)); err != nil {
+		return err
+	}
+
+	return s.refreshTokenRepo.RevokeOtherFamilies(ctx, user.ID, sessionID)
+}
+
+// DeleteAccount deletes the user after checking their password again
+func (s *UserService) DeleteAccount(ctx context.Context, id uuid.UUID, password, clientIP string) error {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.recheckPassword(ctx, user, password, clientIP); err != nil {
+		return err
+	}
+
+	if err := s.refreshTokenRepo.RevokeByUserID(ctx, user.ID); err != nil {
+		return err
+	}
+
+	return s.userRepo.Delete(ctx, user.ID)
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/tanerincode/e2e-app/internal/mail"
+	"github.com/tanerincode/e2e-app/internal/model"
+)
+
+var (
+	// ErrInvalidVerificationToken is returned when an email verification
+	// token can't be verified or no longer matches the account
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+	// ErrEmailNotVerified is returned by Login when the account's email has
+	// not been verified and the configuration requires it
+	ErrEmailNotVerified = errors.New("email address has not been verified")
+)
+
+// emailVerificationClaims are the claims of an email verification token. The
+// email is included so a token stops working if the address changes.
+type emailVerificationClaims struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// VerifyEmail marks the account the verification token was issued for as verified
+func (s *authService) VerifyEmail(ctx context.Context, verificationToken string) error {
+	claims := &emailVerificationClaims{}
+	token, err := jwt.ParseWithClaims(verificationToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(s.config.EmailVerificationSecret), nil
+	})
+	if err != nil || !token.Valid {
+		return ErrInvalidVerificationToken
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return ErrInvalidVerificationToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil || !strings.EqualFold(user.Email, claims.Email) {
+		return ErrInvalidVerificationToken
+	}
+
+	if user.EmailVerified {
+		return nil
+	}
+
+	return s.userRepo.MarkEmailVerified(ctx, user.ID, time.Now())
+}
+
+// ResendVerification sends a new verification email. It succeeds silently
+// for unknown or already verified addresses so it can't be used to probe
+// which emails have accounts.
+func (s *authService) ResendVerification(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil || user.EmailVerified {
+		return nil
+	}
+	return s.sendVerificationEmail(ctx, user)
+}
+
+// sendVerificationEmail mails the user a link with a signed verification token
+func (s *authService) sendVerificationEmail(ctx context.Context, user *model.User) error {
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, emailVerificationClaims{
+		UserID: user.ID.String(),
+		Email:  user.Email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.config.GetEmailVerificationExpiration())),
+		},
+	})
+
+	tokenString, err := token.SignedString([]byte(s.config.EmailVerificationSecret))
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/api/v1/auth/verify-email?token=%s",
+		strings.TrimSuffix(s.config.PublicURL, "/"), url.QueryEscape(tokenString))
+
+	err = s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\nThe link expires in %s.",
+			user.FirstName, link, s.config.GetEmailVerificationExpiration()),
+	})
+	if err != nil {
+		log.Printf("Failed to send verification email to %s: %v", user.Email, err)
+		return err
+	}
+	return nil
+}
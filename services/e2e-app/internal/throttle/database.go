@@ -0,0 +1,100 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tanerincode/e2e-app/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DatabaseStore keeps failure counts in the login_attempts table so that every
+// replica sees the same counts
+type DatabaseStore struct {
+	db *gorm.DB
+}
+
+// NewDatabaseStore creates a DatabaseStore backed by db
+func NewDatabaseStore(db *gorm.DB) *DatabaseStore {
+	return &DatabaseStore{db: db}
+}
+
+// Get returns the entry for key
+func (s *DatabaseStore) Get(ctx context.Context, key string) (Entry, error) {
+	var attempt model.LoginAttempt
+	err := s.db.WithContext(ctx).Where(&model.LoginAttempt{Key: key}).First(&attempt).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return Entry{}, nil
+		}
+		return Entry{}, err
+	}
+	return toEntry(&attempt), nil
+}
+
+// RecordFailure adds a failure to key. The increment is a single upsert so
+// concurrent failures on different replicas are all counted.
+func (s *DatabaseStore) RecordFailure(ctx context.Context, key string, window time.Duration) (Entry, error) {
+	now := time.Now()
+	attempt := model.LoginAttempt{
+		Key:           key,
+		Failures:      1,
+		LastFailureAt: now,
+	}
+
+	err := s.db.WithContext(ctx).Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "key"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"failures": gorm.Expr(
+					"CASE WHEN login_attempts.last_failure_at < ? THEN 1 ELSE login_attempts.failures + 1 END",
+					now.Add(-window),
+				),
+				"last_failure_at": now,
+			}),
+		},
+		clause.Returning{},
+	).Create(&attempt).Error
+	if err != nil {
+		return Entry{}, err
+	}
+	return toEntry(&attempt), nil
+}
+
+// Lock blocks key until the given time
+func (s *DatabaseStore) Lock(ctx context.Context, key string, until time.Time) error {
+	return s.db.WithContext(ctx).
+		Model(&model.LoginAttempt{}).
+		Where(&model.LoginAttempt{Key: key}).
+		Update("locked_until", until).Error
+}
+
+// Reset forgets key
+func (s *DatabaseStore) Reset(ctx context.Context, key string) error {
+	return s.db.WithContext(ctx).
+		Where(&model.LoginAttempt{Key: key}).
+		Delete(&model.LoginAttempt{}).Error
+}
+
+// DeleteStale removes keys whose last failure is older than window and that
+// aren't locked, which is every key a later failure would start over for.
+// Keys that only ever failed are otherwise never removed.
+func (s *DatabaseStore) DeleteStale(ctx context.Context, window time.Duration) error {
+	now := time.Now()
+	return s.db.WithContext(ctx).
+		Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", now.Add(-window), now).
+		Delete(&model.LoginAttempt{}).Error
+}
+
+func toEntry(attempt *model.LoginAttempt) Entry {
+	entry := Entry{
+		Failures:      attempt.Failures,
+		LastFailureAt: attempt.LastFailureAt,
+	}
+	if attempt.LockedUntil != nil {
+		entry.LockedUntil = *attempt.LockedUntil
+	}
+	return entry
+}
@@ -0,0 +1,100 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// pruneInterval is how often the memory store drops entries that are neither
+// locked nor within their failure window
+const pruneInterval = time.Minute
+
+// MemoryStore keeps failure counts in process memory
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastPrune time.Time
+}
+
+type memoryEntry struct {
+	Entry
+	window time.Duration
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries:   make(map[string]*memoryEntry),
+		lastPrune: time.Now(),
+	}
+}
+
+// Get returns the entry for key
+func (s *MemoryStore) Get(ctx context.Context, key string) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.entries[key]; ok {
+		return entry.Entry, nil
+	}
+	return Entry{}, nil
+}
+
+// RecordFailure adds a failure to key
+func (s *MemoryStore) RecordFailure(ctx context.Context, key string, window time.Duration) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.prune(now)
+
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &memoryEntry{}
+		s.entries[key] = entry
+	}
+	if now.Sub(entry.LastFailureAt) > window {
+		entry.Failures = 0
+	}
+	entry.Failures++
+	entry.LastFailureAt = now
+	entry.window = window
+
+	return entry.Entry, nil
+}
+
+// Lock blocks key until the given time
+func (s *MemoryStore) Lock(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.entries[key]; ok {
+		entry.LockedUntil = until
+	}
+	return nil
+}
+
+// Reset forgets key
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// prune drops stale entries so that keys from one-off failures don't pile up.
+// The caller must hold s.mu.
+func (s *MemoryStore) prune(now time.Time) {
+	if now.Sub(s.lastPrune) < pruneInterval {
+		return
+	}
+	s.lastPrune = now
+
+	for key, entry := range s.entries {
+		if now.Sub(entry.LastFailureAt) > entry.window && now.After(entry.LockedUntil) {
+			delete(s.entries, key)
+		}
+	}
+}
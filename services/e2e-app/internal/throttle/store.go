@@ -0,0 +1,28 @@
+package throttle
+
+import (
+	"context"
+	"time"
+)
+
+// Entry is the failure state stored for a throttling key
+type Entry struct {
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+}
+
+// Store persists failure counts. The in-memory store is enough for a single
+// replica; multiple replicas need a shared store so that an attacker can't
+// spread attempts across instances.
+type Store interface {
+	// Get returns the entry for key, or a zero Entry if there is none
+	Get(ctx context.Context, key string) (Entry, error)
+	// RecordFailure adds a failure to key and returns the updated entry. The
+	// count starts over when the previous failure is older than window.
+	RecordFailure(ctx context.Context, key string, window time.Duration) (Entry, error)
+	// Lock blocks key until the given time
+	Lock(ctx context.Context, key string, until time.Time) error
+	// Reset forgets every failure recorded for key
+	Reset(ctx context.Context, key string) error
+}
@@ -0,0 +1,160 @@
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tanerincode/e2e-app/internal/config"
+	"gorm.io/gorm"
+)
+
+// LockedError is returned while a login key is locked out
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+// Policy describes when a key gets locked and for how long. Once Threshold
+// failures have been counted within Window, every further failure locks the
+// key for BaseLockout, doubling each time up to MaxLockout.
+type Policy struct {
+	Threshold   int
+	Window      time.Duration
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+}
+
+// lockout returns how long a key with the given number of failures is locked
+func (p Policy) lockout(failures int) time.Duration {
+	if p.Threshold <= 0 || failures < p.Threshold {
+		return 0
+	}
+
+	lockout := p.BaseLockout
+	for i := p.Threshold; i < failures && lockout < p.MaxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > p.MaxLockout {
+		lockout = p.MaxLockout
+	}
+	return lockout
+}
+
+// Limiter throttles logins per account and per client IP
+type Limiter struct {
+	store   Store
+	account Policy
+	ip      Policy
+}
+
+// NewLimiter creates a Limiter using the given store and policies
+func NewLimiter(store Store, account, ip Policy) *Limiter {
+	return &Limiter{
+		store:   store,
+		account: account,
+		ip:      ip,
+	}
+}
+
+// New returns the limiter configured by cfg, with the store selected by
+// cfg.LoginThrottleStore
+func New(cfg *config.Config, db *gorm.DB) (*Limiter, error) {
+	var store Store
+	switch cfg.LoginThrottleStore {
+	case "memory", "":
+		store = NewMemoryStore()
+	case "database":
+		store = NewDatabaseStore(db)
+	default:
+		return nil, fmt.Errorf("unknown login throttle store %q", cfg.LoginThrottleStore)
+	}
+
+	window := cfg.GetLoginFailureWindow()
+	baseLockout := cfg.GetLoginLockoutBase()
+	maxLockout := cfg.GetLoginLockoutMax()
+
+	account := Policy{
+		Threshold:   cfg.LoginMaxAccountFailures,
+		Window:      window,
+		BaseLockout: baseLockout,
+		MaxLockout:  maxLockout,
+	}
+	ip := Policy{
+		Threshold:   cfg.LoginMaxIPFailures,
+		Window:      window,
+		BaseLockout: baseLockout,
+		MaxLockout:  maxLockout,
+	}
+	return NewLimiter(store, account, ip), nil
+}
+
+// Check returns a *LockedError if either the account or the client IP is
+// currently locked out
+func (l *Limiter) Check(ctx context.Context, email, clientIP string) error {
+	now := time.Now()
+	for _, key := range l.keys(email, clientIP) {
+		entry, err := l.store.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		if now.Before(entry.LockedUntil) {
+			return &LockedError{RetryAfter: entry.LockedUntil.Sub(now)}
+		}
+	}
+	return nil
+}
+
+// Fail records a failed login for the account and the client IP, locking
+// either of them once its policy threshold is reached
+func (l *Limiter) Fail(ctx context.Context, email, clientIP string) error {
+	if err := l.fail(ctx, accountKey(email), l.account); err != nil {
+		return err
+	}
+	if clientIP == "" {
+		return nil
+	}
+	return l.fail(ctx, ipKey(clientIP), l.ip)
+}
+
+// Succeed clears the failures of the account. The IP count is left alone,
+// otherwise an attacker could reset it by logging into an account of their own.
+func (l *Limiter) Succeed(ctx context.Context, email string) error {
+	return l.store.Reset(ctx, accountKey(email))
+}
+
+func (l *Limiter) fail(ctx context.Context, key string, policy Policy) error {
+	if policy.Threshold <= 0 {
+		return nil
+	}
+
+	entry, err := l.store.RecordFailure(ctx, key, policy.Window)
+	if err != nil {
+		return err
+	}
+
+	if lockout := policy.lockout(entry.Failures); lockout > 0 {
+		return l.store.Lock(ctx, key, time.Now().Add(lockout))
+	}
+	return nil
+}
+
+func (l *Limiter) keys(email, clientIP string) []string {
+	keys := []string{accountKey(email)}
+	if clientIP != "" {
+		keys = append(keys, ipKey(clientIP))
+	}
+	return keys
+}
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(clientIP string) string {
+	return "ip:" + clientIP
+}
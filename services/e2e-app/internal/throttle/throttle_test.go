@@ -0,0 +1,211 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tanerincode/e2e-app/internal/config"
+	"github.com/tanerincode/e2e-app/internal/model"
+	"github.com/tanerincode/e2e-app/internal/repository"
+)
+
+const testIP = "192.0.2.1"
+
+// step is a single login outcome fed to the limiter
+type step struct {
+	email   string
+	succeed bool
+}
+
+// fails returns n failed logins of email
+func fails(email string, n int) []step {
+	steps := make([]step, n)
+	for i := range steps {
+		steps[i] = step{email: email}
+	}
+	return steps
+}
+
+func TestLimiterLockout(t *testing.T) {
+	account := Policy{Threshold: 3, Window: time.Minute, BaseLockout: time.Minute, MaxLockout: time.Hour}
+	ip := Policy{Threshold: 5, Window: time.Minute, BaseLockout: time.Minute, MaxLockout: time.Hour}
+
+	tests := []struct {
+		name  string
+		steps [][]step
+		// check is the account checked once every step has run
+		check      string
+		wantLocked bool
+	}{
+		{
+			name:       "below the account threshold",
+			steps:      [][]step{fails("a@example.com", 2)},
+			check:      "a@example.com",
+			wantLocked: false,
+		},
+		{
+			name:       "at the account threshold",
+			steps:      [][]step{fails("a@example.com", 3)},
+			check:      "a@example.com",
+			wantLocked: true,
+		},
+		{
+			name:       "account lockout doesn't lock other accounts",
+			steps:      [][]step{fails("a@example.com", 3)},
+			check:      "b@example.com",
+			wantLocked: false,
+		},
+		{
+			name:       "account lockout ignores email case",
+			steps:      [][]step{fails("A@Example.com", 3)},
+			check:      "a@example.com",
+			wantLocked: true,
+		},
+		{
+			name: "success resets the account count",
+			steps: [][]step{
+				fails("a@example.com", 2),
+				{{email: "a@example.com", succeed: true}},
+				fails("a@example.com", 2),
+			},
+			check:      "a@example.com",
+			wantLocked: false,
+		},
+		{
+			name: "at the IP threshold across accounts",
+			steps: [][]step{
+				fails("a@example.com", 2),
+				fails("b@example.com", 2),
+				fails("c@example.com", 1),
+			},
+			check:      "d@example.com",
+			wantLocked: true,
+		},
+		{
+			name: "success leaves the IP count alone",
+			steps: [][]step{
+				fails("a@example.com", 2),
+				fails("b@example.com", 2),
+				{{email: "b@example.com", succeed: true}},
+				fails("c@example.com", 1),
+			},
+			check:      "d@example.com",
+			wantLocked: true,
+		},
+	}
+
+	stores := []struct {
+		name string
+		new  func(t *testing.T) Store
+	}{
+		{name: "memory", new: func(t *testing.T) Store { return NewMemoryStore() }},
+		{name: "database", new: newDatabaseStore},
+	}
+
+	for _, store := range stores {
+		for _, tt := range tests {
+			t.Run(store.name+"/"+tt.name, func(t *testing.T) {
+				ctx := context.Background()
+				limiter := NewLimiter(store.new(t), account, ip)
+
+				for _, steps := range tt.steps {
+					for _, s := range steps {
+						var err error
+						if s.succeed {
+							err = limiter.Succeed(ctx, s.email)
+						} else {
+							err = limiter.Fail(ctx, s.email, testIP)
+						}
+						if err != nil {
+							t.Fatalf("failed to record login of %s: %v", s.email, err)
+						}
+					}
+				}
+
+				err := limiter.Check(ctx, tt.check, testIP)
+				var locked *LockedError
+				if gotLocked := errors.As(err, &locked); gotLocked != tt.wantLocked {
+					t.Fatalf("Check(%s) = %v, want locked %v", tt.check, err, tt.wantLocked)
+				}
+				if err != nil && !errors.As(err, &locked) {
+					t.Fatalf("Check(%s) failed: %v", tt.check, err)
+				}
+				if locked != nil && (locked.RetryAfter <= 0 || locked.RetryAfter > account.BaseLockout) {
+					t.Errorf("RetryAfter = %v, want up to %v", locked.RetryAfter, account.BaseLockout)
+				}
+			})
+		}
+	}
+}
+
+func TestPolicyLockoutGrows(t *testing.T) {
+	policy := Policy{Threshold: 3, BaseLockout: time.Minute, MaxLockout: 5 * time.Minute}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 2, want: 0},
+		{failures: 3, want: time.Minute},
+		{failures: 4, want: 2 * time.Minute},
+		{failures: 5, want: 4 * time.Minute},
+		{failures: 6, want: 5 * time.Minute},
+		{failures: 20, want: 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := policy.lockout(tt.failures); got != tt.want {
+			t.Errorf("lockout(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestDatabaseStoreDeleteStale(t *testing.T) {
+	ctx := context.Background()
+	store := newDatabaseStore(t).(*DatabaseStore)
+
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+	attempts := []struct {
+		attempt  model.LoginAttempt
+		wantKept bool
+	}{
+		{attempt: model.LoginAttempt{Key: "ip:stale", Failures: 1, LastFailureAt: now.Add(-time.Hour)}},
+		{attempt: model.LoginAttempt{Key: "ip:lock-expired", Failures: 5, LastFailureAt: now.Add(-time.Hour), LockedUntil: &past}},
+		{attempt: model.LoginAttempt{Key: "ip:recent", Failures: 1, LastFailureAt: past}, wantKept: true},
+		{attempt: model.LoginAttempt{Key: "ip:locked", Failures: 5, LastFailureAt: now.Add(-time.Hour), LockedUntil: &future}, wantKept: true},
+	}
+	for _, a := range attempts {
+		if err := store.db.Create(&a.attempt).Error; err != nil {
+			t.Fatalf("failed to create %s: %v", a.attempt.Key, err)
+		}
+	}
+
+	if err := store.DeleteStale(ctx, 10*time.Minute); err != nil {
+		t.Fatalf("DeleteStale failed: %v", err)
+	}
+
+	for _, a := range attempts {
+		entry, err := store.Get(ctx, a.attempt.Key)
+		if err != nil {
+			t.Fatalf("failed to get %s: %v", a.attempt.Key, err)
+		}
+		if kept := entry.Failures > 0; kept != a.wantKept {
+			t.Errorf("%s kept %v, want %v", a.attempt.Key, kept, a.wantKept)
+		}
+	}
+}
+
+// newDatabaseStore returns a DatabaseStore on a fresh mock database
+func newDatabaseStore(t *testing.T) Store {
+	t.Helper()
+
+	db, err := repository.NewDB(&config.Config{AppEnv: "development", MockDB: true})
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	t.Cleanup(func() { repository.CloseDB(db) })
+	return NewDatabaseStore(db)
+}
@@ -1,13 +1,24 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"log"
+	"net"
+	"net/http"
+	"os"
 
-	"github.com/gin-gonic/gin"
+	"github.com/tanerincode/e2e-pkg/health"
+	"github.com/tanerincode/e2e-pkg/lifecycle"
+	"github.com/tanerincode/e2e-pkg/metrics"
+	"github.com/tanerincode/e2e-pkg/migration"
+	"github.com/tanerincode/e2e-pkg/tracing"
+	"github.com/tanerincode/e2e-profile/internal/auth"
 	"github.com/tanerincode/e2e-profile/internal/config"
 	"github.com/tanerincode/e2e-profile/internal/grpc/client"
 	"github.com/tanerincode/e2e-profile/internal/handler"
 	"github.com/tanerincode/e2e-profile/internal/repository"
+	"github.com/tanerincode/e2e-profile/internal/schema"
 	"github.com/tanerincode/e2e-profile/internal/service"
 )
 
@@ -21,54 +32,99 @@ func main() {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	// Handle the migrate subcommand
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migration.RunCommand(context.Background(), db, schema.Migrations, os.Args[2:], os.Stdout); err != nil {
+			log.Fatalf("Migrate failed: %v", err)
+		}
+		return
+	}
+
+	// Make sure the schema matches this binary
+	if err := migration.Ensure(context.Background(), db, schema.Migrations, cfg.DBAutoMigrate); err != nil {
+		log.Fatalf("Refusing to start: %v", err)
+	}
+
+	// Initialize tracing
+	exporter, err := tracing.NewExporter(context.Background(), cfg.TracingExporter)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	tracerProvider := tracing.Setup(cfg.TracingServiceName, exporter, cfg.GetTracingSampleRatio())
+
+	// Record query timings and trace queries
+	if err := db.Use(metrics.GormPlugin()); err != nil {
+		log.Fatalf("Failed to instrument database: %v", err)
+	}
+	if err := db.Use(tracing.GormPlugin()); err != nil {
+		log.Fatalf("Failed to instrument database: %v", err)
+	}
+
 	// Initialize repositories
 	profileRepo := repository.NewProfileRepository(db)
 
 	// Initialize gRPC client
-	authClient, err := client.NewAuthClient(cfg.AuthGRPCAddr)
+	var grpcTLS *tls.Config
+	if cfg.AuthGRPCTLSEnabled() {
+		grpcTLS, err = client.TLSConfig(cfg.AuthGRPCCAFile, cfg.AuthGRPCCertFile, cfg.AuthGRPCKeyFile, cfg.AuthGRPCServerName)
+		if err != nil {
+			log.Fatalf("Failed to configure auth gRPC TLS: %v", err)
+		}
+	}
+	authClient, err := client.NewAuthClient(cfg.AuthGRPCAddr, cfg.AuthServiceCredential, grpcTLS, cfg.AuthGRPCAllowInsecureCredential)
 	if err != nil {
 		log.Fatalf("Failed to connect to auth gRPC service: %v", err)
 	}
-	defer authClient.Close()
+
+	// Initialize token verification. In local mode revoked tokens are
+	// checked against a copy of the auth service's revocation list.
+	var revocations *auth.RevocationList
+	if cfg.AuthMode == config.AuthModeLocal && cfg.AuthRevocationCheck {
+		revocations = auth.NewRevocationList(authClient, cfg.GetAuthRevocationMaxStaleness())
+		syncCtx, cancel := context.WithTimeout(context.Background(), cfg.GetAuthRevocationSyncInterval())
+		if err := revocations.Sync(syncCtx); err != nil {
+			log.Printf("Failed to sync the revocation list, refusing tokens until it succeeds: %v", err)
+		}
+		cancel()
+	}
+	verifier := auth.NewTokenVerifier(cfg, authClient, revocations)
 
 	// Initialize services
-	profileService := service.NewProfileService(cfg, profileRepo)
+	profileService := service.NewProfileService(authClient, profileRepo)
 
 	// Initialize handlers
 	profileHandler := handler.NewProfileHandler(profileService)
 
-	// Setup router
-	r := gin.Default()
+	// Register the checks readiness depends on
+	checks := health.NewRegistry(cfg.GetHealthCheckTimeout())
+	checks.Register("database", func(ctx context.Context) error { return repository.PingDB(ctx, db) })
+	checks.Register("auth_grpc", authClient.CheckHealth)
+	healthHandler := health.NewHandler(checks)
 
-	// Health check
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status": "healthy",
-		})
-	})
+	// Setup router
+	r := handler.NewRouter(cfg.TracingServiceName, verifier, profileHandler, healthHandler)
 
-	// API routes
-	api := r.Group("/api/v1")
-	{
-		// Public routes
-		profiles := api.Group("/profiles")
-		{
-			profiles.GET("/:id", profileHandler.GetProfile)
-		}
+	// Open the listener up front so a port in use fails startup
+	listener, err := net.Listen("tcp", ":"+cfg.Port)
+	if err != nil {
+		log.Fatalf("Failed to listen for HTTP: %v", err)
+	}
 
-		// Protected routes
-		protected := api.Group("/profiles")
-		protected.Use(handler.AuthMiddleware(cfg, authClient))
-		{
-			protected.POST("/", profileHandler.CreateProfile)
-			protected.PUT("/:id", profileHandler.UpdateProfile)
-			protected.DELETE("/:id", profileHandler.DeleteProfile)
-		}
+	// Run the server until SIGTERM or SIGINT, then drain it and close the
+	// database and the auth service connection. Spans are flushed last.
+	manager := lifecycle.New(cfg.GetShutdownTimeout())
+	manager.AddCloser("tracing", func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.GetShutdownTimeout())
+		defer cancel()
+		return tracerProvider.Shutdown(ctx)
+	})
+	manager.AddCloser("database", func() error { return repository.CloseDB(db) })
+	manager.AddCloser("auth service connection", authClient.Close)
+	manager.AddServer("HTTP", lifecycle.HTTPServer(&http.Server{Handler: r}, listener))
+	if revocations != nil {
+		manager.AddServer("Revocation sync", lifecycle.Job("Revocation sync", cfg.GetAuthRevocationSyncInterval(), revocations.Sync))
 	}
 
-	// Start server
 	log.Printf("HTTP server starting on port %s", cfg.Port)
-	if err := r.Run(":" + cfg.Port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
-	}
-}
\ No newline at end of file
+	os.Exit(manager.Run())
+}
@@ -0,0 +1,191 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// authServiceDir is the e2e-app module, relative to this package
+const authServiceDir = "../../e2e-app"
+
+// authBinary is the e2e-app binary TestMain builds
+var authBinary string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "e2e-integration")
+	if err != nil {
+		log.Fatalf("failed to create build directory: %v", err)
+	}
+	authBinary = filepath.Join(dir, "e2e-app")
+
+	build := exec.Command("go", "build", "-o", authBinary, "./cmd/api")
+	build.Dir = authServiceDir
+	if output, err := build.CombinedOutput(); err != nil {
+		log.Fatalf("failed to build e2e-app: %v\n%s", err, output)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// authService is a running e2e-app process
+type authService struct {
+	grpcAddress string
+	httpURL     string
+}
+
+// startAuthService runs e2e-app with an in-memory database until the test
+// ends. serviceCredentials maps service names to their credentials, like
+// SERVICE_CREDENTIALS; env adds further settings.
+func startAuthService(t *testing.T, serviceCredentials map[string]string, env ...string) *authService {
+	t.Helper()
+
+	var credentials []string
+	for name, credential := range serviceCredentials {
+		credentials = append(credentials, name+":"+credential)
+	}
+	grpcPort, httpPort := freePort(t), freePort(t)
+
+	cmd := exec.Command(authBinary)
+	cmd.Env = append(os.Environ(),
+		"APP_ENV=development",
+		"MOCK_DB=true",
+		"MAIL_DRIVER=log",
+		"PORT="+httpPort,
+		"GRPC_PORT="+grpcPort,
+		"SERVICE_CREDENTIALS="+strings.Join(credentials, ","),
+	)
+	cmd.Env = append(cmd.Env, env...)
+	output := &lockedBuffer{}
+	cmd.Stdout, cmd.Stderr = output, output
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start e2e-app: %v", err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+
+	// Shut down the way Kubernetes would, so buffered spans are flushed
+	t.Cleanup(func() {
+		cmd.Process.Signal(syscall.SIGTERM)
+		select {
+		case <-exited:
+		case <-time.After(10 * time.Second):
+			cmd.Process.Kill()
+			<-exited
+		}
+		if t.Failed() {
+			t.Logf("e2e-app output:\n%s", output.String())
+		}
+	})
+
+	service := &authService{
+		grpcAddress: "127.0.0.1:" + grpcPort,
+		httpURL:     "http://127.0.0.1:" + httpPort,
+	}
+	deadline := time.Now().Add(20 * time.Second)
+	for {
+		resp, err := http.Get(service.httpURL + "/readyz")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return service
+			}
+		}
+		select {
+		case <-exited:
+			t.Fatalf("e2e-app exited during startup:\n%s", output.String())
+		case <-time.After(50 * time.Millisecond):
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("e2e-app didn't become ready:\n%s", output.String())
+		}
+	}
+}
+
+// createUser registers a user over the auth service's HTTP API and returns
+// its ID
+func (s *authService) createUser(t *testing.T, email, firstName, lastName string) uuid.UUID {
+	t.Helper()
+
+	body, err := json.Marshal(map[string]string{
+		"email":      email,
+		"password":   "correct horse battery staple",
+		"first_name": firstName,
+		"last_name":  lastName,
+	})
+	if err != nil {
+		t.Fatalf("failed to encode registration: %v", err)
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, s.httpURL+"/api/v1/auth/register", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create registration request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to register %s: %v", email, err)
+	}
+	defer resp.Body.Close()
+
+	var user struct {
+		ID uuid.UUID `json:"id"`
+	}
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("failed to register %s: status %d", email, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		t.Fatalf("failed to decode registered user: %v", err)
+	}
+	return user.ID
+}
+
+// freePort returns a TCP port nothing listens on right now
+func freePort(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	defer listener.Close()
+	return fmt.Sprint(listener.Addr().(*net.TCPAddr).Port)
+}
+
+// lockedBuffer collects the output of a process
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,6 @@
+// Package integration tests the profile service against a running auth
+// service. The tests build e2e-app from the neighbouring directory, start it
+// with its in-memory database and only run with the integration build tag:
+//
+//	go test -tags=integration ./integration/...
+package integration
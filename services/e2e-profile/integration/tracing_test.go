@@ -0,0 +1,197 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tanerincode/e2e-pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+)
+
+// span is a finished span of either service
+type span struct {
+	service  string
+	name     string
+	kind     trace.SpanKind
+	traceID  trace.TraceID
+	spanID   trace.SpanID
+	parentID trace.SpanID
+}
+
+// collector is an OTLP trace receiver the auth service exports its spans to
+type collector struct {
+	collectortrace.UnimplementedTraceServiceServer
+
+	mu    sync.Mutex
+	spans []span
+}
+
+func startCollector(t *testing.T) (*collector, string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen for spans: %v", err)
+	}
+	c := &collector{}
+	server := grpc.NewServer()
+	collectortrace.RegisterTraceServiceServer(server, c)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return c, listener.Addr().String()
+}
+
+func (c *collector) Export(ctx context.Context, req *collectortrace.ExportTraceServiceRequest) (*collectortrace.ExportTraceServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, resourceSpans := range req.ResourceSpans {
+		service := ""
+		for _, attr := range resourceSpans.Resource.GetAttributes() {
+			if attr.Key == "service.name" {
+				service = attr.Value.GetStringValue()
+			}
+		}
+		for _, scopeSpans := range resourceSpans.ScopeSpans {
+			for _, s := range scopeSpans.Spans {
+				// OTLP numbers span kinds the same way the trace API does
+				recorded := span{service: service, name: s.Name, kind: trace.SpanKind(s.Kind)}
+				copy(recorded.traceID[:], s.TraceId)
+				copy(recorded.spanID[:], s.SpanId)
+				copy(recorded.parentID[:], s.ParentSpanId)
+				c.spans = append(c.spans, recorded)
+			}
+		}
+	}
+	return &collectortrace.ExportTraceServiceResponse{}, nil
+}
+
+// inTrace returns the spans received so far that belong to traceID
+func (c *collector) inTrace(traceID trace.TraceID) []span {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var spans []span
+	for _, s := range c.spans {
+		if s.traceID == traceID {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+func TestProfileLookupIsOneTrace(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// The auth service exports to the collector, the profile service to
+	// memory. The tracer provider has to be in place before the router and
+	// client are built.
+	received, collectorAddress := startCollector(t)
+	authService := startAuthService(t, map[string]string{"e2e-profile": profileCredential},
+		"TRACING_EXPORTER=otlp",
+		"OTEL_EXPORTER_OTLP_ENDPOINT=http://"+collectorAddress,
+		"OTEL_BSP_SCHEDULE_DELAY=100",
+	)
+	userID := authService.createUser(t, "traced@example.com", "Traced", "User")
+
+	exporter, err := tracing.NewExporter(context.Background(), tracing.ExporterMemory)
+	if err != nil {
+		t.Fatalf("failed to create exporter: %v", err)
+	}
+	provider := tracing.Setup("e2e-profile", exporter, 1)
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+	router, _ := newProfileRouter(t, authService, profileCredential)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/profiles/"+userID.String(), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/v1/profiles/:id: status %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if err := provider.ForceFlush(context.Background()); err != nil {
+		t.Fatalf("failed to flush spans: %v", err)
+	}
+
+	// The trace starts at the profile service's request span
+	bySpanID := make(map[trace.SpanID]span)
+	var request *span
+	for _, stub := range exporter.(*tracetest.InMemoryExporter).GetSpans() {
+		recorded := span{
+			name:     stub.Name,
+			kind:     stub.SpanKind,
+			traceID:  stub.SpanContext.TraceID(),
+			spanID:   stub.SpanContext.SpanID(),
+			parentID: stub.Parent.SpanID(),
+		}
+		if value, ok := stub.Resource.Set().Value(attribute.Key("service.name")); ok {
+			recorded.service = value.AsString()
+		}
+		bySpanID[recorded.spanID] = recorded
+		if recorded.name == "/api/v1/profiles/:id" {
+			request = &recorded
+		}
+	}
+	if request == nil {
+		t.Fatalf("no span for the profile request")
+	}
+
+	// Wait for the auth service to export the user query of that trace
+	var query *span
+	deadline := time.Now().Add(10 * time.Second)
+	for query == nil {
+		for _, recorded := range received.inTrace(request.traceID) {
+			bySpanID[recorded.spanID] = recorded
+			if recorded.name == "gorm.query" {
+				query = &recorded
+			}
+		}
+		if query == nil {
+			if time.Now().After(deadline) {
+				t.Fatalf("the auth service exported no user query in trace %s", request.traceID)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+
+	// Walk up from the user query to the request: every hop must be the
+	// parent of the next one, in the same trace
+	hops := []struct {
+		what    string
+		service string
+		kind    trace.SpanKind
+		name    string
+	}{
+		{what: "user query", service: "e2e-app", kind: trace.SpanKindClient, name: "gorm.query"},
+		{what: "auth service call", service: "e2e-app", kind: trace.SpanKindServer, name: "auth.UserService/GetUser"},
+		{what: "profile service call", service: "e2e-profile", kind: trace.SpanKindClient, name: "auth.UserService/GetUser"},
+		{what: "HTTP request", service: "e2e-profile", kind: trace.SpanKindServer, name: "/api/v1/profiles/:id"},
+	}
+	current := *query
+	for i, hop := range hops {
+		if current.service != hop.service || current.kind != hop.kind || current.name != hop.name {
+			t.Fatalf("%s: got %s %s span %q, want %s %s span %q", hop.what, current.service, current.kind, current.name, hop.service, hop.kind, hop.name)
+		}
+		if i == len(hops)-1 {
+			break
+		}
+		parent, ok := bySpanID[current.parentID]
+		if !ok {
+			t.Fatalf("%s has no recorded parent", hop.what)
+		}
+		current = parent
+	}
+	if current.spanID != request.spanID || current.parentID.IsValid() {
+		t.Errorf("the trace doesn't start at the profile request")
+	}
+}
@@ -0,0 +1,108 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/tanerincode/e2e-pkg/health"
+	"github.com/tanerincode/e2e-profile/internal/auth"
+	"github.com/tanerincode/e2e-profile/internal/grpc/client"
+	"github.com/tanerincode/e2e-profile/internal/handler"
+	"github.com/tanerincode/e2e-profile/internal/model"
+	"github.com/tanerincode/e2e-profile/internal/repository"
+	"github.com/tanerincode/e2e-profile/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// profileCredential is the credential the auth service knows the profile
+// service by
+const profileCredential = "profile-service-credential"
+
+// noProfiles is a repository.ProfileRepository without any profiles, so
+// profile lookups only return what the auth service knows about the user
+type noProfiles struct{}
+
+func (noProfiles) Create(ctx context.Context, profile *model.ProfileData) error { return nil }
+func (noProfiles) GetByID(ctx context.Context, id uuid.UUID) (*model.ProfileData, error) {
+	return nil, repository.ErrProfileNotFound
+}
+func (noProfiles) GetByUserID(ctx context.Context, userID uuid.UUID) (*model.ProfileData, error) {
+	return nil, repository.ErrProfileNotFound
+}
+func (noProfiles) Update(ctx context.Context, profile *model.ProfileData) error { return nil }
+func (noProfiles) Delete(ctx context.Context, id uuid.UUID) error               { return nil }
+
+// newProfileRouter wires the profile service's router to the auth service,
+// calling it with credential
+func newProfileRouter(t *testing.T, authService *authService, credential string) (*gin.Engine, *client.AuthClient) {
+	t.Helper()
+
+	// The local connection has no TLS, like the compose setup
+	authClient, err := client.NewAuthClient(authService.grpcAddress, credential, nil, true)
+	if err != nil {
+		t.Fatalf("failed to create auth client: %v", err)
+	}
+	t.Cleanup(func() { authClient.Close() })
+
+	profileHandler := handler.NewProfileHandler(service.NewProfileService(authClient, noProfiles{}))
+	verifier := auth.NewGRPCVerifier(authClient, "e2e-profile")
+	healthHandler := health.NewHandler(health.NewRegistry(time.Second))
+	return handler.NewRouter("e2e-profile", verifier, profileHandler, healthHandler), authClient
+}
+
+func TestUserServiceRequiresServiceCredential(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		credential string
+		wantCode   codes.Code
+		wantStatus int
+	}{
+		{name: "missing credential", credential: "", wantCode: codes.Unauthenticated, wantStatus: http.StatusInternalServerError},
+		{name: "wrong credential", credential: "not-the-credential", wantCode: codes.Unauthenticated, wantStatus: http.StatusInternalServerError},
+		{name: "valid credential", credential: profileCredential, wantCode: codes.OK, wantStatus: http.StatusOK},
+	}
+
+	authService := startAuthService(t, map[string]string{"e2e-profile": profileCredential})
+	userID := authService.createUser(t, "lookup@example.com", "Lookup", "User")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, authClient := newProfileRouter(t, authService, tt.credential)
+
+			// The lookup itself
+			_, err := authClient.GetUser(context.Background(), userID)
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("GetUser: got %v (%v), want %v", got, err, tt.wantCode)
+			}
+
+			// And the public profile route built on it
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/profiles/"+userID.String(), nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("GET /api/v1/profiles/:id: status %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var profile model.UserProfile
+			if err := json.Unmarshal(w.Body.Bytes(), &profile); err != nil {
+				t.Fatalf("failed to decode profile: %v", err)
+			}
+			if profile.ID != userID || profile.Email != "lookup@example.com" || profile.FirstName != "Lookup" {
+				t.Errorf("got profile %+v, want the registered user", profile)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// minRefetchInterval limits how often unknown kids or a failing auth service
+// can trigger another fetch
+const minRefetchInterval = 10 * time.Second
+
+// jwk is a single JSON Web Key as published by the auth service
+type jwk struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+}
+
+// JWKSCache fetches and caches the auth service's verification keys. Keys are
+// refreshed after the refresh interval, or early when a token refers to an
+// unknown kid (which is what happens right after a key rotation). If a
+// refresh fails the previously fetched keys keep being used. Only one fetch
+// runs at a time, and at most one starts per minRefetchInterval.
+type JWKSCache struct {
+	url      string
+	interval time.Duration
+	client   *http.Client
+
+	// refreshMu is held for the whole of a fetch, so callers missing at the
+	// same time wait for it instead of starting their own
+	refreshMu sync.Mutex
+
+	mu          sync.RWMutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+}
+
+// NewJWKSCache creates a cache for the key set published at url
+func NewJWKSCache(url string, interval time.Duration) *JWKSCache {
+	return &JWKSCache{
+		url:      url,
+		interval: interval,
+		client:   &http.Client{Timeout: 5 * time.Second},
+		keys:     make(map[string]crypto.PublicKey),
+	}
+}
+
+// Key returns the public key for kid
+func (c *JWKSCache) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	c.mu.RLock()
+	key, ok := c.keys[kid]
+	stale := time.Since(c.fetchedAt) > c.interval
+	canRetry := time.Since(c.attemptedAt) > minRefetchInterval
+	c.mu.RUnlock()
+
+	if ok && (!stale || !canRetry) {
+		return key, nil
+	}
+
+	// An unknown kid waits for a fetch already in progress, which may be the
+	// one that brings the key in
+	c.refreshIfDue(ctx)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if key, ok := c.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key: %q", kid)
+}
+
+// refreshIfDue fetches the key set unless a fetch was attempted within
+// minRefetchInterval, which is the case for callers that waited on another
+// caller's fetch
+func (c *JWKSCache) refreshIfDue(ctx context.Context) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	c.mu.RLock()
+	due := time.Since(c.attemptedAt) > minRefetchInterval
+	c.mu.RUnlock()
+	if !due {
+		return
+	}
+
+	if err := c.refresh(ctx); err != nil {
+		log.Printf("Failed to refresh JWKS from %s: %v", c.url, err)
+	}
+}
+
+// refresh fetches the key set and replaces the cached keys. Callers must
+// hold refreshMu.
+func (c *JWKSCache) refresh(ctx context.Context) error {
+	c.mu.Lock()
+	c.attemptedAt = time.Now()
+	c.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		key, err := k.publicKey()
+		if err != nil {
+			log.Printf("Skipping JWKS key %q: %v", k.KeyID, err)
+			continue
+		}
+		keys[k.KeyID] = key
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.fetchedAt = time.Now()
+	c.mu.Unlock()
+	return nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+	}
+}
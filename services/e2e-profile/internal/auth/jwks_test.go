@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newJWKSServer serves a key set with a single Ed25519 key under kid and
+// counts the fetches. Each fetch takes a moment so concurrent callers overlap.
+func newJWKSServer(t *testing.T, kid string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		json.NewEncoder(w).Encode(map[string][]jwk{
+			"keys": {{
+				KeyType: "OKP",
+				KeyID:   kid,
+				Curve:   "Ed25519",
+				X:       base64.RawURLEncoding.EncodeToString(public),
+			}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, &fetches
+}
+
+func TestJWKSCacheCollapsesRefetches(t *testing.T) {
+	tests := []struct {
+		name string
+		kid  string
+		// wantKey is whether the lookups should find a key
+		wantKey bool
+	}{
+		{name: "kid published after a rotation", kid: "current", wantKey: true},
+		{name: "kid that was never published", kid: "forged", wantKey: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, fetches := newJWKSServer(t, "current")
+			cache := NewJWKSCache(server.URL, time.Hour)
+
+			const callers = 20
+			var wg sync.WaitGroup
+			var found atomic.Int32
+			for i := 0; i < callers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if _, err := cache.Key(context.Background(), tt.kid); err == nil {
+						found.Add(1)
+					}
+				}()
+			}
+			wg.Wait()
+
+			if got := fetches.Load(); got != 1 {
+				t.Errorf("concurrent lookups fetched the key set %d times, want 1", got)
+			}
+			want := int32(0)
+			if tt.wantKey {
+				want = callers
+			}
+			if got := found.Load(); got != want {
+				t.Errorf("%d of %d lookups found the key, want %d", got, callers, want)
+			}
+
+			// Another unknown kid right away must not trigger a fetch
+			if _, err := cache.Key(context.Background(), "another-unknown"); err == nil {
+				t.Error("lookup of an unknown kid succeeded")
+			}
+			if got := fetches.Load(); got != 1 {
+				t.Errorf("unknown kid within the refetch interval fetched again, %d fetches in total", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/tanerincode/e2e-profile/internal/grpc/client"
+)
+
+const (
+	testIssuer   = "http://auth.test"
+	testAudience = "e2e-profile"
+	testKeyID    = "test-key"
+)
+
+// fakeRevocationSource serves a fixed revocation list, or fails with err
+type fakeRevocationSource struct {
+	revocations *client.Revocations
+	err         error
+}
+
+func (s *fakeRevocationSource) ListRevocations(ctx context.Context) (*client.Revocations, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.revocations, nil
+}
+
+// newTestSigner publishes an Ed25519 key as testKeyID and returns a JWKS
+// cache for it along with a function that signs access tokens with it
+func newTestSigner(t *testing.T) (*JWKSCache, func(claims accessClaims) string) {
+	t.Helper()
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string][]jwk{
+			"keys": {{
+				KeyType: "OKP",
+				KeyID:   testKeyID,
+				Curve:   "Ed25519",
+				X:       base64.RawURLEncoding.EncodeToString(public),
+			}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	sign := func(claims accessClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+		token.Header["kid"] = testKeyID
+		signed, err := token.SignedString(private)
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		return signed
+	}
+	return NewJWKSCache(server.URL, time.Hour), sign
+}
+
+// accessToken returns the claims of an access token issued at issuedAt
+func accessToken(jti, sessionID, userID string, issuedAt time.Time) accessClaims {
+	return accessClaims{
+		Type:      "access",
+		UserID:    userID,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   userID,
+			Issuer:    testIssuer,
+			Audience:  jwt.ClaimStrings{testAudience},
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(issuedAt.Add(time.Hour)),
+		},
+	}
+}
+
+// clientToken turns claims into those of a token issued to an OAuth client
+func clientToken(claims accessClaims) accessClaims {
+	claims.ClientID = "client"
+	return claims
+}
+
+func TestLocalVerifierChecksRevocations(t *testing.T) {
+	keys, sign := newTestSigner(t)
+	issuedAt := time.Now().Add(-time.Minute).Truncate(time.Second)
+
+	source := &fakeRevocationSource{revocations: &client.Revocations{
+		TokenIDs:   []string{"revoked-jti"},
+		SessionIDs: []string{"revoked-session"},
+		Users: map[string]time.Time{
+			"revoked-user":    issuedAt,
+			"signed-in-again": issuedAt.Add(-time.Second),
+		},
+	}}
+	revocations := NewRevocationList(source, 2*time.Minute)
+	if err := revocations.Sync(context.Background()); err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+	verifier := NewLocalVerifier(keys, revocations, testIssuer, testAudience)
+
+	tests := []struct {
+		name    string
+		claims  accessClaims
+		wantErr error
+	}{
+		{name: "not revoked", claims: accessToken("jti", "session", "user", issuedAt)},
+		{name: "revoked token", claims: accessToken("revoked-jti", "session", "user", issuedAt), wantErr: ErrTokenRevoked},
+		{name: "revoked session", claims: accessToken("jti", "revoked-session", "user", issuedAt), wantErr: ErrTokenRevoked},
+		{name: "token of a revoked user", claims: accessToken("jti", "session", "revoked-user", issuedAt), wantErr: ErrTokenRevoked},
+		{name: "token issued after the user was revoked", claims: accessToken("jti", "session", "signed-in-again", issuedAt)},
+		{name: "token without jti", claims: accessToken("", "session", "user", issuedAt), wantErr: ErrInvalidToken},
+		{name: "token issued to an OAuth client", claims: clientToken(accessToken("jti", "session", "user", issuedAt)), wantErr: ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := verifier.Verify(context.Background(), sign(tt.claims))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && identity.UserID != tt.claims.UserID {
+				t.Errorf("got user %q, want %q", identity.UserID, tt.claims.UserID)
+			}
+		})
+	}
+}
+
+func TestRevocationListDuringOutage(t *testing.T) {
+	start := time.Now()
+	now := start
+	source := &fakeRevocationSource{revocations: &client.Revocations{
+		TokenIDs: []string{"revoked-jti"},
+	}}
+	revocations := NewRevocationList(source, 2*time.Minute)
+	revocations.now = func() time.Time { return now }
+
+	check := func(jti string) error {
+		return revocations.Check(jti, "session", "user", start)
+	}
+
+	// Nothing can be told before the first sync
+	if err := check("jti"); !errors.Is(err, ErrRevocationUnavailable) {
+		t.Fatalf("before the first sync: got %v, want %v", err, ErrRevocationUnavailable)
+	}
+	if err := revocations.Sync(context.Background()); err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+
+	// The auth service goes away; the last list is used within the window
+	source.err = errors.New("connection refused")
+	now = start.Add(time.Minute)
+	if err := revocations.Sync(context.Background()); err == nil {
+		t.Fatalf("sync succeeded during the outage")
+	}
+	if err := check("jti"); err != nil {
+		t.Errorf("within the window: got %v, want the token accepted", err)
+	}
+	if err := check("revoked-jti"); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("within the window: got %v, want %v", err, ErrTokenRevoked)
+	}
+
+	// Past the window nothing is accepted
+	now = start.Add(2*time.Minute + time.Second)
+	if err := check("jti"); !errors.Is(err, ErrRevocationUnavailable) {
+		t.Errorf("past the window: got %v, want %v", err, ErrRevocationUnavailable)
+	}
+
+	// The next successful sync brings the list back, including what was
+	// revoked meanwhile
+	source.err = nil
+	source.revocations = &client.Revocations{TokenIDs: []string{"revoked-jti", "jti"}}
+	if err := revocations.Sync(context.Background()); err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+	if err := check("other-jti"); err != nil {
+		t.Errorf("after recovery: got %v, want the token accepted", err)
+	}
+	if err := check("jti"); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("after recovery: got %v, want %v", err, ErrTokenRevoked)
+	}
+}
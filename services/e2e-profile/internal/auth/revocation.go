@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/tanerincode/e2e-profile/internal/grpc/client"
+)
+
+var (
+	// ErrTokenRevoked is returned for a token on the revocation list
+	ErrTokenRevoked = errors.New("token has been revoked")
+	// ErrRevocationUnavailable is returned when the revocation list is too
+	// old to tell whether a token was revoked
+	ErrRevocationUnavailable = errors.New("revocation status unavailable")
+)
+
+// RevocationSource provides the auth service's revocation list
+type RevocationSource interface {
+	ListRevocations(ctx context.Context) (*client.Revocations, error)
+}
+
+// RevocationList is a local copy of the auth service's revocation list, so
+// checking a token doesn't cost a call. Sync replaces the copy. When syncs
+// keep failing the last copy is used until it is older than maxStaleness;
+// after that every check fails with ErrRevocationUnavailable, which bounds
+// how long a token revoked during an outage stays usable.
+type RevocationList struct {
+	source       RevocationSource
+	maxStaleness time.Duration
+	now          func() time.Time
+
+	mu         sync.RWMutex
+	tokenIDs   map[string]struct{}
+	sessionIDs map[string]struct{}
+	users      map[string]time.Time
+	syncedAt   time.Time
+}
+
+// NewRevocationList creates an empty list. Checks fail until the first Sync
+// succeeds.
+func NewRevocationList(source RevocationSource, maxStaleness time.Duration) *RevocationList {
+	return &RevocationList{
+		source:       source,
+		maxStaleness: maxStaleness,
+		now:          time.Now,
+	}
+}
+
+// Sync fetches the revocation list and replaces the local copy. A failed
+// sync keeps the previous copy.
+func (l *RevocationList) Sync(ctx context.Context) error {
+	// The copy is as old as the moment it was asked for, however long the
+	// call takes
+	started := l.now()
+	revocations, err := l.source.ListRevocations(ctx)
+	if err != nil {
+		return err
+	}
+
+	tokenIDs := make(map[string]struct{}, len(revocations.TokenIDs))
+	for _, id := range revocations.TokenIDs {
+		tokenIDs[id] = struct{}{}
+	}
+	sessionIDs := make(map[string]struct{}, len(revocations.SessionIDs))
+	for _, id := range revocations.SessionIDs {
+		sessionIDs[id] = struct{}{}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokenIDs = tokenIDs
+	l.sessionIDs = sessionIDs
+	l.users = revocations.Users
+	l.syncedAt = started
+	return nil
+}
+
+// Check reports whether a token with the jti, session and user, issued at
+// issuedAt, was revoked
+func (l *RevocationList) Check(tokenID, sessionID, userID string, issuedAt time.Time) error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if l.syncedAt.IsZero() || l.now().Sub(l.syncedAt) > l.maxStaleness {
+		return ErrRevocationUnavailable
+	}
+	if _, ok := l.tokenIDs[tokenID]; ok {
+		return ErrTokenRevoked
+	}
+	if _, ok := l.sessionIDs[sessionID]; ok {
+		return ErrTokenRevoked
+	}
+	if revokedAt, ok := l.users[userID]; ok && !issuedAt.After(revokedAt) {
+		return ErrTokenRevoked
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/tanerincode/e2e-profile/internal/config"
+	"github.com/tanerincode/e2e-profile/internal/grpc/client"
+)
+
+// ErrInvalidToken is returned when a token fails verification
+var ErrInvalidToken = errors.New("invalid token")
+
+// Identity describes the caller a verified token belongs to. AMR lists the
+// authentication methods used to sign in, e.g. "pwd" and "otp". Permissions
+// are the effective permissions granted by the auth service, including the
+// ones that come with the caller's roles.
+type Identity struct {
+	UserID      string
+	AMR         []string
+	Roles       []string
+	Permissions []string
+}
+
+// HasPermission reports whether the caller was granted the permission
+func (i *Identity) HasPermission(permission string) bool {
+	for _, granted := range i.Permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenVerifier verifies bearer tokens issued by the auth service
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (*Identity, error)
+}
+
+// NewTokenVerifier returns the verifier selected by cfg.AuthMode. In local
+// mode revocations, if not nil, is checked for revoked tokens; the caller
+// keeps it synced.
+func NewTokenVerifier(cfg *config.Config, authClient *client.AuthClient, revocations *RevocationList) TokenVerifier {
+	if cfg.AuthMode == config.AuthModeLocal {
+		log.Printf("Verifying tokens locally against %s", cfg.GetAuthJWKSURL())
+		keys := NewJWKSCache(cfg.GetAuthJWKSURL(), cfg.GetJWKSRefreshInterval())
+		return NewLocalVerifier(keys, revocations, cfg.GetAuthIssuer(), cfg.AuthAudience)
+	}
+	return NewGRPCVerifier(authClient, cfg.AuthAudience)
+}
+
+// GRPCVerifier asks the auth service to validate every token
+type GRPCVerifier struct {
+	authClient *client.AuthClient
+	audience   string
+}
+
+// NewGRPCVerifier creates a verifier backed by the auth gRPC service that
+// accepts tokens issued for audience
+func NewGRPCVerifier(authClient *client.AuthClient, audience string) *GRPCVerifier {
+	return &GRPCVerifier{
+		authClient: authClient,
+		audience:   audience,
+	}
+}
+
+// Verify validates the token via gRPC
+func (v *GRPCVerifier) Verify(ctx context.Context, token string) (*Identity, error) {
+	info, err := v.authClient.ValidateToken(ctx, token, v.audience)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{
+		UserID:      info.UserID,
+		AMR:         info.AMR,
+		Roles:       info.Roles,
+		Permissions: info.Permissions,
+	}, nil
+}
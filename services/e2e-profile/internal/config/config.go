@@ -2,6 +2,17 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Token verification modes
+const (
+	// AuthModeGRPC validates every token with the auth service over gRPC
+	AuthModeGRPC = "grpc"
+	// AuthModeLocal verifies tokens against the auth service's cached JWKS
+	AuthModeLocal = "local"
 )
 
 // Config holds the application configuration
@@ -10,12 +21,69 @@ type Config struct {
 	AuthGRPCAddr   string
 	Port           string
 
+	// ShutdownTimeout bounds how long in-flight requests may take to finish
+	// after SIGTERM
+	ShutdownTimeout string
+
+	// HealthCheckTimeout bounds each dependency check behind /readyz
+	HealthCheckTimeout string
+
+	// Tracing. TracingExporter is "none", "otlp", "stdout" or "memory"; the
+	// OTLP exporter reads its endpoint from the standard
+	// OTEL_EXPORTER_OTLP_* variables.
+	TracingExporter    string
+	TracingServiceName string
+	TracingSampleRatio string
+
+	// AuthServiceCredential authenticates this service to the auth service's
+	// internal gRPC services. It must be listed in the auth service's
+	// SERVICE_CREDENTIALS.
+	AuthServiceCredential string
+
+	// TLS on the gRPC channel to the auth service. AuthGRPCTLS turns it on
+	// with the system roots; a CA file, client certificate or server name
+	// turn it on as well. The client certificate is presented for mutual TLS.
+	AuthGRPCTLS        bool
+	AuthGRPCCAFile     string
+	AuthGRPCCertFile   string
+	AuthGRPCKeyFile    string
+	AuthGRPCServerName string
+
+	// AuthGRPCAllowInsecureCredential lets the service credential travel over
+	// a plaintext channel. Only meant for local development.
+	AuthGRPCAllowInsecureCredential bool
+
+	// Token verification
+	AuthMode            string
+	AuthJWKSURL         string
+	JWKSRefreshInterval string
+
+	// In local mode AuthRevocationCheck keeps a copy of the auth service's
+	// revocation list, synced every AuthRevocationSyncInterval, and refuses
+	// revoked tokens. The check fails open for a bounded time only: while
+	// the auth service can't be reached, tokens are checked against the last
+	// list synced, so a token revoked during the outage is still accepted;
+	// once that list is older than AuthRevocationMaxStaleness every request
+	// is refused with 503 until a sync succeeds again.
+	AuthRevocationCheck        bool
+	AuthRevocationSyncInterval string
+	AuthRevocationMaxStaleness string
+
+	// Tokens must name AuthAudience among their audiences. AuthIssuer
+	// defaults to AuthServiceURL and must match the auth service's
+	// PUBLIC_URL when the two differ.
+	AuthAudience string
+	AuthIssuer   string
+
 	// Database configuration
 	DBHost     string
 	DBPort     string
 	DBUser     string
 	DBPassword string
 	DBName     string
+
+	// Migrations
+	DBAutoMigrate bool
 }
 
 // New creates a new Config with values from environment or defaults
@@ -25,15 +93,131 @@ func New() *Config {
 		AuthGRPCAddr:   getEnv("AUTH_GRPC_ADDR", "localhost:50051"),
 		Port:           getEnv("PORT", "8081"),
 
+		ShutdownTimeout:    getEnv("SHUTDOWN_TIMEOUT", "20s"),
+		HealthCheckTimeout: getEnv("HEALTH_CHECK_TIMEOUT", "2s"),
+
+		// Tracing settings
+		TracingExporter:    getEnv("TRACING_EXPORTER", "none"),
+		TracingServiceName: getEnv("OTEL_SERVICE_NAME", "e2e-profile"),
+		TracingSampleRatio: getEnv("TRACING_SAMPLE_RATIO", "1"),
+
+		AuthServiceCredential: getEnv("AUTH_SERVICE_CREDENTIAL", ""),
+
+		// gRPC TLS settings - the server name overrides the host of
+		// AUTH_GRPC_ADDR when checking the server certificate
+		AuthGRPCTLS:        getBoolEnv("AUTH_GRPC_TLS", false),
+		AuthGRPCCAFile:     getEnv("AUTH_GRPC_CA_FILE", ""),
+		AuthGRPCCertFile:   getEnv("AUTH_GRPC_CERT_FILE", ""),
+		AuthGRPCKeyFile:    getEnv("AUTH_GRPC_KEY_FILE", ""),
+		AuthGRPCServerName: getEnv("AUTH_GRPC_SERVER_NAME", ""),
+
+		AuthGRPCAllowInsecureCredential: getBoolEnv("AUTH_GRPC_ALLOW_INSECURE_CREDENTIAL", false),
+
+		// Token verification - local mode requires the auth service to sign
+		// tokens with asymmetric keys
+		AuthMode:            getEnv("AUTH_MODE", AuthModeGRPC),
+		AuthJWKSURL:         getEnv("AUTH_JWKS_URL", ""),
+		JWKSRefreshInterval: getEnv("JWKS_REFRESH_INTERVAL", "5m"),
+		AuthAudience:        getEnv("AUTH_AUDIENCE", "e2e-profile"),
+		AuthIssuer:          getEnv("AUTH_ISSUER", ""),
+
+		// Revocation checks in local mode
+		AuthRevocationCheck:        getBoolEnv("AUTH_REVOCATION_CHECK", true),
+		AuthRevocationSyncInterval: getEnv("AUTH_REVOCATION_SYNC_INTERVAL", "15s"),
+		AuthRevocationMaxStaleness: getEnv("AUTH_REVOCATION_MAX_STALENESS", "2m"),
+
 		// Database defaults - typically overridden by environment in production
 		DBHost:     getEnv("DB_HOST", "localhost"),
 		DBPort:     getEnv("DB_PORT", "5432"),
 		DBUser:     getEnv("DB_USER", "postgres"),
 		DBPassword: getEnv("DB_PASSWORD", "postgres"),
 		DBName:     getEnv("DB_NAME", "e2e_profile"),
+
+		// Migration settings
+		DBAutoMigrate: getBoolEnv("DB_AUTO_MIGRATE", false),
 	}
 }
 
+// AuthGRPCTLSEnabled reports whether the channel to the auth service uses TLS
+func (c *Config) AuthGRPCTLSEnabled() bool {
+	return c.AuthGRPCTLS || c.AuthGRPCCAFile != "" || c.AuthGRPCCertFile != "" || c.AuthGRPCKeyFile != "" || c.AuthGRPCServerName != ""
+}
+
+// GetAuthIssuer returns the issuer tokens must carry, derived from the auth
+// service URL unless set explicitly
+func (c *Config) GetAuthIssuer() string {
+	if c.AuthIssuer != "" {
+		return strings.TrimSuffix(c.AuthIssuer, "/")
+	}
+	return strings.TrimSuffix(c.AuthServiceURL, "/")
+}
+
+// GetAuthJWKSURL returns the JWKS URL, derived from the auth service URL
+// unless set explicitly
+func (c *Config) GetAuthJWKSURL() string {
+	if c.AuthJWKSURL != "" {
+		return c.AuthJWKSURL
+	}
+	return strings.TrimSuffix(c.AuthServiceURL, "/") + "/.well-known/jwks.json"
+}
+
+// GetJWKSRefreshInterval returns the parsed JWKS refresh interval
+func (c *Config) GetJWKSRefreshInterval() time.Duration {
+	duration, err := time.ParseDuration(c.JWKSRefreshInterval)
+	if err != nil {
+		return 5 * time.Minute // Default to 5 minutes
+	}
+	return duration
+}
+
+// GetAuthRevocationSyncInterval returns the parsed interval between syncs of
+// the revocation list
+func (c *Config) GetAuthRevocationSyncInterval() time.Duration {
+	duration, err := time.ParseDuration(c.AuthRevocationSyncInterval)
+	if err != nil || duration <= 0 {
+		return 15 * time.Second // Default to 15 seconds
+	}
+	return duration
+}
+
+// GetAuthRevocationMaxStaleness returns how old the revocation list may get
+// before tokens are refused
+func (c *Config) GetAuthRevocationMaxStaleness() time.Duration {
+	duration, err := time.ParseDuration(c.AuthRevocationMaxStaleness)
+	if err != nil || duration <= 0 {
+		return 2 * time.Minute // Default to 2 minutes
+	}
+	return duration
+}
+
+// GetShutdownTimeout returns the parsed shutdown drain timeout
+func (c *Config) GetShutdownTimeout() time.Duration {
+	duration, err := time.ParseDuration(c.ShutdownTimeout)
+	if err != nil {
+		return 20 * time.Second // Default to 20 seconds
+	}
+	return duration
+}
+
+// GetHealthCheckTimeout returns the parsed timeout of a single health check
+func (c *Config) GetHealthCheckTimeout() time.Duration {
+	duration, err := time.ParseDuration(c.HealthCheckTimeout)
+	if err != nil {
+		return 2 * time.Second // Default to 2 seconds
+	}
+	return duration
+}
+
+// GetTracingSampleRatio returns the share of new traces to sample, between
+// 0 and 1
+func (c *Config) GetTracingSampleRatio() float64 {
+	ratio, err := strconv.ParseFloat(c.TracingSampleRatio, 64)
+	if err != nil || ratio < 0 || ratio > 1 {
+		return 1 // Default to sampling every trace
+	}
+	return ratio
+}
+
 // Helper to get environment variable with fallback
 func getEnv(key, fallback string) string {
 	if value, exists := os.LookupEnv(key); exists {
@@ -41,3 +225,15 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// Helper to get boolean environment variable with fallback
+func getBoolEnv(key string, fallback bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		parsedValue, err := strconv.ParseBool(value)
+		if err != nil {
+			return fallback
+		}
+		return parsedValue
+	}
+	return fallback
+}
@@ -2,55 +2,230 @@ package client
 
 import (
 	"context"
-	"errors"
+	"crypto/tls"
 	"fmt"
+	"time"
 
-	pb "github.com/tanerincode/e2e-profile/internal/grpc/proto"
+	pb "github.com/tanerincode/e2e-pkg/grpc/proto"
+	"github.com/tanerincode/e2e-pkg/metrics"
+	"github.com/tanerincode/e2e-pkg/tracing"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
-// AuthClient is a gRPC client for the auth service
+// AuthClient is a gRPC client for the auth service. Besides validating
+// tokens it reads user accounts through the auth service's UserService.
 type AuthClient struct {
 	client pb.AuthServiceClient
+	users  pb.UserServiceClient
+	health healthpb.HealthClient
 	conn   *grpc.ClientConn
 }
 
-// NewAuthClient creates a new gRPC client for the auth service
-func NewAuthClient(address string) (*AuthClient, error) {
+// TokenError is returned when the auth service rejects a token, as opposed to
+// the call itself failing
+type TokenError struct {
+	Code    string
+	Message string
+}
+
+func (e *TokenError) Error() string {
+	return e.Message
+}
+
+// TokenInfo describes a token the auth service accepted
+type TokenInfo struct {
+	UserID      string
+	AMR         []string
+	Roles       []string
+	Permissions []string
+}
+
+// Introspection describes a token as the auth service's introspection
+// reports it. Fields beyond Active are only set for active tokens.
+type Introspection struct {
+	Active      bool
+	TokenType   string
+	Subject     string
+	Username    string
+	Issuer      string
+	Audience    []string
+	IssuedAt    time.Time
+	ExpiresAt   time.Time
+	TokenID     string
+	SessionID   string
+	ClientID    string
+	Scope       string
+	AMR         []string
+	Roles       []string
+	Permissions []string
+}
+
+// Revocations is the auth service's list of revoked access tokens that
+// haven't expired yet. A token is revoked if its jti is in TokenIDs, its
+// session is in SessionIDs, or its user is in Users and the token was issued
+// at or before the time given there.
+type Revocations struct {
+	TokenIDs    []string
+	SessionIDs  []string
+	Users       map[string]time.Time
+	GeneratedAt time.Time
+}
+
+// NewAuthClient creates a new gRPC client for the auth service. The service
+// credential, if set, is sent with every call; the auth service requires it
+// for reading users. A nil tlsConfig connects in plaintext, which refuses to
+// send the credential unless allowInsecureCredential is set.
+func NewAuthClient(address, serviceCredential string, tlsConfig *tls.Config, allowInsecureCredential bool) (*AuthClient, error) {
+	transport := insecure.NewCredentials()
+	if tlsConfig != nil {
+		transport = credentials.NewTLS(tlsConfig)
+	}
+
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(transport),
+		grpc.WithChainUnaryInterceptor(metrics.UnaryClientInterceptor()),
+		grpc.WithStatsHandler(tracing.GRPCClientHandler()),
+	}
+	if serviceCredential != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(bearerCredential{
+			credential:    serviceCredential,
+			allowInsecure: allowInsecureCredential,
+		}))
+	}
+
 	// Use the recommended NewClient method
-	conn, err := grpc.NewClient(
-		address,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
+	conn, err := grpc.NewClient(address, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to auth service: %w", err)
 	}
 
 	return &AuthClient{
 		client: pb.NewAuthServiceClient(conn),
+		users:  pb.NewUserServiceClient(conn),
+		health: healthpb.NewHealthClient(conn),
 		conn:   conn,
 	}, nil
 }
 
-// ValidateToken validates a JWT token with the auth service
-func (c *AuthClient) ValidateToken(ctx context.Context, token string) (bool, string, error) {
+// ValidateToken validates a JWT token with the auth service. The token must
+// have been issued for the audience. A rejected token is reported as a
+// *TokenError.
+func (c *AuthClient) ValidateToken(ctx context.Context, token, audience string) (*TokenInfo, error) {
 	resp, err := c.client.ValidateToken(ctx, &pb.TokenRequest{
-		Token: token,
+		Token:    token,
+		Audience: audience,
 	})
 	if err != nil {
-		return false, "", fmt.Errorf("failed to validate token: %w", err)
+		return nil, fmt.Errorf("failed to validate token: %w", err)
 	}
 
 	if !resp.Valid {
-		errorMsg := "token invalid"
+		tokenErr := &TokenError{Code: "invalid_token", Message: "token invalid"}
 		if resp.Error != nil {
-			errorMsg = resp.Error.Message
+			tokenErr.Code = resp.Error.Code
+			tokenErr.Message = resp.Error.Message
 		}
-		return false, "", errors.New(errorMsg)
+		return nil, tokenErr
+	}
+
+	return &TokenInfo{
+		UserID:      resp.UserId,
+		AMR:         resp.Amr,
+		Roles:       resp.Roles,
+		Permissions: resp.Permissions,
+	}, nil
+}
+
+// IntrospectToken asks the auth service to describe an access or refresh
+// token. The hint is "access_token" or "refresh_token" and may be empty.
+func (c *AuthClient) IntrospectToken(ctx context.Context, token, tokenTypeHint string) (*Introspection, error) {
+	resp, err := c.client.IntrospectToken(ctx, &pb.IntrospectRequest{
+		Token:         token,
+		TokenTypeHint: tokenTypeHint,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to introspect token: %w", err)
+	}
+	if !resp.Active {
+		return &Introspection{Active: false}, nil
 	}
 
-	return true, resp.UserId, nil
+	introspection := &Introspection{
+		Active:      true,
+		TokenType:   resp.TokenType,
+		Subject:     resp.Subject,
+		Username:    resp.Username,
+		Issuer:      resp.Issuer,
+		Audience:    resp.Audience,
+		TokenID:     resp.TokenId,
+		SessionID:   resp.SessionId,
+		ClientID:    resp.ClientId,
+		Scope:       resp.Scope,
+		AMR:         resp.Amr,
+		Roles:       resp.Roles,
+		Permissions: resp.Permissions,
+	}
+	if resp.IssuedAt != nil {
+		introspection.IssuedAt = resp.IssuedAt.AsTime()
+	}
+	if resp.ExpiresAt != nil {
+		introspection.ExpiresAt = resp.ExpiresAt.AsTime()
+	}
+	return introspection, nil
+}
+
+// ListRevocations fetches the auth service's revocation list. The auth
+// service requires the service credential for it.
+func (c *AuthClient) ListRevocations(ctx context.Context) (*Revocations, error) {
+	resp, err := c.client.ListRevocations(ctx, &pb.ListRevocationsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revocations: %w", err)
+	}
+
+	revocations := &Revocations{
+		TokenIDs:    resp.TokenIds,
+		SessionIDs:  resp.SessionIds,
+		Users:       make(map[string]time.Time, len(resp.Users)),
+		GeneratedAt: resp.GeneratedAt.AsTime(),
+	}
+	for _, user := range resp.Users {
+		revocations.Users[user.UserId] = user.RevokedAt.AsTime()
+	}
+	return revocations, nil
+}
+
+// CheckHealth asks the auth service's gRPC health service whether the token
+// service is serving
+func (c *AuthClient) CheckHealth(ctx context.Context) error {
+	resp, err := c.health.Check(ctx, &healthpb.HealthCheckRequest{
+		Service: pb.AuthService_ServiceDesc.ServiceName,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check auth service health: %w", err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("auth service is %s", resp.Status)
+	}
+	return nil
+}
+
+// bearerCredential sends a service credential as "authorization: Bearer" metadata
+type bearerCredential struct {
+	credential    string
+	allowInsecure bool
+}
+
+func (c bearerCredential) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + c.credential}, nil
+}
+
+// RequireTransportSecurity keeps the credential off plaintext channels, where
+// anyone on the network could read and replay it, unless explicitly allowed
+func (c bearerCredential) RequireTransportSecurity() bool {
+	return !c.allowInsecure
 }
 
 // Close closes the gRPC connection
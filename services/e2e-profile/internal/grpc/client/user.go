@@ -0,0 +1,142 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/tanerincode/e2e-pkg/grpc/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ErrUserNotFound is returned when the auth service has no user with the ID
+var ErrUserNotFound = errors.New("user not found")
+
+// User is a user account as the auth service reports it
+type User struct {
+	ID            uuid.UUID
+	Email         string
+	FirstName     string
+	LastName      string
+	EmailVerified bool
+	Disabled      bool
+	Roles         []string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// UserFilter narrows down and pages a user listing. Zero values are left to
+// the auth service's defaults.
+type UserFilter struct {
+	Email         string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Disabled      *bool
+	Page          int
+	PageSize      int
+}
+
+// UserPage is a page of a user listing along with the number of matching
+// users in total
+type UserPage struct {
+	Users    []*User
+	Total    int64
+	Page     int
+	PageSize int
+}
+
+// GetUser fetches a single user. A user that doesn't exist is reported as
+// ErrUserNotFound.
+func (c *AuthClient) GetUser(ctx context.Context, id uuid.UUID) (*User, error) {
+	resp, err := c.users.GetUser(ctx, &pb.GetUserRequest{Id: id.String()})
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return fromProtoUser(resp)
+}
+
+// BatchGetUsers fetches several users in one call. Users that don't exist are
+// left out of the result, which is keyed by user ID.
+func (c *AuthClient) BatchGetUsers(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*User, error) {
+	req := &pb.BatchGetUsersRequest{Ids: make([]string, 0, len(ids))}
+	for _, id := range ids {
+		req.Ids = append(req.Ids, id.String())
+	}
+
+	resp, err := c.users.BatchGetUsers(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+
+	users := make(map[uuid.UUID]*User, len(resp.Users))
+	for _, pbUser := range resp.Users {
+		user, err := fromProtoUser(pbUser)
+		if err != nil {
+			return nil, err
+		}
+		users[user.ID] = user
+	}
+	return users, nil
+}
+
+// ListUsers fetches a page of users, newest first
+func (c *AuthClient) ListUsers(ctx context.Context, filter UserFilter) (*UserPage, error) {
+	req := &pb.ListUsersRequest{
+		Email:    filter.Email,
+		Disabled: filter.Disabled,
+		Page:     int32(filter.Page),
+		PageSize: int32(filter.PageSize),
+	}
+	if filter.CreatedAfter != nil {
+		req.CreatedAfter = timestamppb.New(*filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		req.CreatedBefore = timestamppb.New(*filter.CreatedBefore)
+	}
+
+	resp, err := c.users.ListUsers(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	page := &UserPage{
+		Users:    make([]*User, 0, len(resp.Users)),
+		Total:    resp.Total,
+		Page:     int(resp.Page),
+		PageSize: int(resp.PageSize),
+	}
+	for _, pbUser := range resp.Users {
+		user, err := fromProtoUser(pbUser)
+		if err != nil {
+			return nil, err
+		}
+		page.Users = append(page.Users, user)
+	}
+	return page, nil
+}
+
+func fromProtoUser(pbUser *pb.User) (*User, error) {
+	id, err := uuid.Parse(pbUser.Id)
+	if err != nil {
+		return nil, fmt.Errorf("auth service returned an invalid user ID %q", pbUser.Id)
+	}
+
+	return &User{
+		ID:            id,
+		Email:         pbUser.Email,
+		FirstName:     pbUser.FirstName,
+		LastName:      pbUser.LastName,
+		EmailVerified: pbUser.EmailVerified,
+		Disabled:      pbUser.Disabled,
+		Roles:         pbUser.Roles,
+		CreatedAt:     pbUser.CreatedAt.AsTime(),
+		UpdatedAt:     pbUser.UpdatedAt.AsTime(),
+	}, nil
+}
@@ -1,17 +1,16 @@
 package handler
 
 import (
-	"context"
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/tanerincode/e2e-profile/internal/config"
-	"github.com/tanerincode/e2e-profile/internal/grpc/client"
+	"github.com/tanerincode/e2e-profile/internal/auth"
 )
 
-// AuthMiddleware validates tokens via gRPC with the auth service
-func AuthMiddleware(cfg *config.Config, authClient *client.AuthClient) gin.HandlerFunc {
+// AuthMiddleware validates tokens with the configured token verifier
+func AuthMiddleware(verifier auth.TokenVerifier) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get authorization header
 		authHeader := c.GetHeader("Authorization")
@@ -31,20 +30,33 @@ func AuthMiddleware(cfg *config.Config, authClient *client.AuthClient) gin.Handl
 
 		token := parts[1]
 
-		// Validate token via gRPC
-		valid, userID, err := authClient.ValidateToken(context.Background(), token)
-		if err != nil || !valid {
-			errMsg := "invalid token"
-			if err != nil {
-				errMsg = err.Error()
-			}
-			c.JSON(http.StatusUnauthorized, gin.H{"error": errMsg})
+		// Validate token
+		identity, err := verifier.Verify(c.Request.Context(), token)
+		if errors.Is(err, auth.ErrRevocationUnavailable) {
+			// The token may be fine; it's this service that can't tell
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			c.Abort()
 			return
 		}
 
-		// Set user ID in context
-		c.Set("user_id", userID)
+		// Set user ID and the full identity in context
+		c.Set("user_id", identity.UserID)
+		c.Set("identity", identity)
 		c.Next()
 	}
+}
+
+// currentIdentity returns the caller's identity set by AuthMiddleware
+func currentIdentity(c *gin.Context) (*auth.Identity, bool) {
+	value, exists := c.Get("identity")
+	if !exists {
+		return nil, false
+	}
+	identity, ok := value.(*auth.Identity)
+	return identity, ok
 }
\ No newline at end of file
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -33,7 +34,7 @@ func (h *ProfileHandler) GetProfile(c *gin.Context) {
 	
 	profile, err := h.profileService.GetProfile(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondProfileError(c, err)
 		return
 	}
 	
@@ -49,14 +50,10 @@ func (h *ProfileHandler) CreateProfile(c *gin.Context) {
 		return
 	}
 	
-	// Ensure valid UUID
-	if profile.UserID == uuid.Nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
-		return
-	}
-	
-	if err := h.profileService.CreateProfile(c.Request.Context(), &profile); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// The profile belongs to the caller unless user_id says otherwise
+	identity, _ := currentIdentity(c)
+	if err := h.profileService.CreateProfile(c.Request.Context(), identity, &profile); err != nil {
+		respondProfileError(c, err)
 		return
 	}
 	
@@ -82,8 +79,9 @@ func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
 	// Ensure ID in path matches body
 	profile.ID = id
 	
-	if err := h.profileService.UpdateProfile(c.Request.Context(), &profile); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	identity, _ := currentIdentity(c)
+	if err := h.profileService.UpdateProfile(c.Request.Context(), identity, &profile); err != nil {
+		respondProfileError(c, err)
 		return
 	}
 	
@@ -100,10 +98,23 @@ func (h *ProfileHandler) DeleteProfile(c *gin.Context) {
 		return
 	}
 	
-	if err := h.profileService.DeleteProfile(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	identity, _ := currentIdentity(c)
+	if err := h.profileService.DeleteProfile(c.Request.Context(), identity, id); err != nil {
+		respondProfileError(c, err)
 		return
 	}
 	
 	c.JSON(http.StatusOK, gin.H{"message": "profile deleted successfully"})
+}
+
+// respondProfileError writes the response for a failed profile change
+func respondProfileError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrProfileNotFound), errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
 }
\ No newline at end of file
@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/tanerincode/e2e-profile/internal/auth"
+	"github.com/tanerincode/e2e-profile/internal/grpc/client"
+	"github.com/tanerincode/e2e-profile/internal/model"
+	"github.com/tanerincode/e2e-profile/internal/repository"
+	"github.com/tanerincode/e2e-profile/internal/service"
+)
+
+// profileStore is an in-memory repository.ProfileRepository. The profile
+// model uses PostgreSQL column types, so there is no SQLite database to run
+// the service against. Every call fails with err when it is set.
+type profileStore struct {
+	profiles map[uuid.UUID]model.ProfileData
+	err      error
+}
+
+func (s *profileStore) Create(ctx context.Context, profile *model.ProfileData) error {
+	if s.err != nil {
+		return s.err
+	}
+	if profile.ID == uuid.Nil {
+		profile.ID = uuid.New()
+	}
+	s.profiles[profile.ID] = *profile
+	return nil
+}
+
+func (s *profileStore) GetByID(ctx context.Context, id uuid.UUID) (*model.ProfileData, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	profile, ok := s.profiles[id]
+	if !ok {
+		return nil, repository.ErrProfileNotFound
+	}
+	return &profile, nil
+}
+
+func (s *profileStore) GetByUserID(ctx context.Context, userID uuid.UUID) (*model.ProfileData, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	for _, profile := range s.profiles {
+		if profile.UserID == userID {
+			return &profile, nil
+		}
+	}
+	return nil, repository.ErrProfileNotFound
+}
+
+func (s *profileStore) Update(ctx context.Context, profile *model.ProfileData) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.profiles[profile.ID] = *profile
+	return nil
+}
+
+func (s *profileStore) Delete(ctx context.Context, id uuid.UUID) error {
+	if s.err != nil {
+		return s.err
+	}
+	delete(s.profiles, id)
+	return nil
+}
+
+// noUsers is a service.UserLookup for routes that don't look users up
+type noUsers struct{}
+
+func (noUsers) GetUser(ctx context.Context, id uuid.UUID) (*client.User, error) {
+	return nil, client.ErrUserNotFound
+}
+
+// tokenIdentities is an auth.TokenVerifier that knows a fixed set of tokens
+type tokenIdentities map[string]*auth.Identity
+
+func (t tokenIdentities) Verify(ctx context.Context, token string) (*auth.Identity, error) {
+	identity, ok := t[token]
+	if !ok {
+		return nil, auth.ErrInvalidToken
+	}
+	return identity, nil
+}
+
+func TestProfileChangesRequireOwnership(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	owner := uuid.New()
+	identities := tokenIdentities{
+		"owner": {UserID: owner.String()},
+		"other": {UserID: uuid.New().String()},
+		"admin": {UserID: uuid.New().String(), Permissions: []string{service.PermissionProfilesManage}},
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		token  string
+		// missing targets a profile that doesn't exist
+		missing    bool
+		storeErr   error
+		wantStatus int
+		// wantKept means the stored profile must be left as it was
+		wantKept bool
+	}{
+		{name: "owner edits", method: http.MethodPut, token: "owner", wantStatus: http.StatusOK},
+		{name: "other user edits", method: http.MethodPut, token: "other", wantStatus: http.StatusForbidden, wantKept: true},
+		{name: "admin edits", method: http.MethodPut, token: "admin", wantStatus: http.StatusOK},
+		{name: "edit of a missing profile", method: http.MethodPut, token: "owner", missing: true, wantStatus: http.StatusNotFound},
+		{name: "edit while the database fails", method: http.MethodPut, token: "owner", storeErr: errors.New("connection refused"), wantStatus: http.StatusInternalServerError},
+		{name: "owner deletes", method: http.MethodDelete, token: "owner", wantStatus: http.StatusOK},
+		{name: "other user deletes", method: http.MethodDelete, token: "other", wantStatus: http.StatusForbidden, wantKept: true},
+		{name: "admin deletes", method: http.MethodDelete, token: "admin", wantStatus: http.StatusOK},
+		{name: "delete of a missing profile", method: http.MethodDelete, token: "other", missing: true, wantStatus: http.StatusNotFound},
+		{name: "delete while the database fails", method: http.MethodDelete, token: "owner", storeErr: errors.New("connection refused"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := model.ProfileData{ID: uuid.New(), UserID: owner, Bio: "original"}
+			store := &profileStore{profiles: map[uuid.UUID]model.ProfileData{stored.ID: stored}}
+			profileHandler := NewProfileHandler(service.NewProfileService(noUsers{}, store))
+
+			r := gin.New()
+			profiles := r.Group("/api/v1/profiles")
+			profiles.Use(AuthMiddleware(identities))
+			profiles.PUT("/:id", profileHandler.UpdateProfile)
+			profiles.DELETE("/:id", profileHandler.DeleteProfile)
+
+			target := stored.ID
+			if tt.missing {
+				target = uuid.New()
+			}
+			// The store only starts failing once the fixture is in place
+			store.err = tt.storeErr
+
+			req := httptest.NewRequest(tt.method, "/api/v1/profiles/"+target.String(), strings.NewReader(`{"bio":"changed"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantKept {
+				if kept, ok := store.profiles[stored.ID]; !ok || kept.Bio != stored.Bio || kept.UserID != owner {
+					t.Errorf("refused change modified the profile: %+v", kept)
+				}
+			}
+			if tt.method == http.MethodPut && tt.wantStatus == http.StatusOK && store.profiles[stored.ID].UserID != owner {
+				t.Errorf("edit moved the profile to user %s", store.profiles[stored.ID].UserID)
+			}
+		})
+	}
+}
+
+func TestCreateProfileBindsOwner(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	caller := uuid.New()
+	other := uuid.New()
+	identities := tokenIdentities{
+		"caller": {UserID: caller.String()},
+		"admin":  {UserID: caller.String(), Permissions: []string{service.PermissionProfilesManage}},
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		body       string
+		wantStatus int
+		// wantOwner is the user the created profile must belong to
+		wantOwner uuid.UUID
+	}{
+		{name: "omitted user_id", token: "caller", body: `{"bio":"new"}`, wantStatus: http.StatusCreated, wantOwner: caller},
+		{name: "own user_id", token: "caller", body: `{"bio":"new","user_id":"` + caller.String() + `"}`, wantStatus: http.StatusCreated, wantOwner: caller},
+		{name: "other user's user_id", token: "caller", body: `{"bio":"new","user_id":"` + other.String() + `"}`, wantStatus: http.StatusForbidden},
+		{name: "other user's user_id with profiles:manage", token: "admin", body: `{"bio":"new","user_id":"` + other.String() + `"}`, wantStatus: http.StatusCreated, wantOwner: other},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &profileStore{profiles: map[uuid.UUID]model.ProfileData{}}
+			profileHandler := NewProfileHandler(service.NewProfileService(noUsers{}, store))
+
+			r := gin.New()
+			profiles := r.Group("/api/v1/profiles")
+			profiles.Use(AuthMiddleware(identities))
+			profiles.POST("", profileHandler.CreateProfile)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/profiles", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusCreated {
+				if len(store.profiles) != 0 {
+					t.Errorf("refused create stored %d profiles", len(store.profiles))
+				}
+				return
+			}
+			if len(store.profiles) != 1 {
+				t.Fatalf("stored %d profiles, want 1", len(store.profiles))
+			}
+			for _, created := range store.profiles {
+				if created.UserID != tt.wantOwner {
+					t.Errorf("profile belongs to %s, want %s", created.UserID, tt.wantOwner)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tanerincode/e2e-pkg/health"
+	"github.com/tanerincode/e2e-pkg/metrics"
+	"github.com/tanerincode/e2e-pkg/tracing"
+	"github.com/tanerincode/e2e-profile/internal/auth"
+)
+
+// NewRouter sets up the HTTP routes. serviceName names the service in the
+// spans of incoming requests.
+func NewRouter(serviceName string, verifier auth.TokenVerifier, profileHandler *ProfileHandler, healthHandler *health.Handler) *gin.Engine {
+	r := gin.Default()
+	r.Use(tracing.HTTPMiddleware(serviceName))
+	r.Use(metrics.HTTPMiddleware())
+
+	// Health checks. /health is kept for probes that still use it.
+	r.GET("/health", healthHandler.Livez)
+	r.GET("/livez", healthHandler.Livez)
+	r.GET("/readyz", healthHandler.Readyz)
+
+	// Prometheus metrics
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	// API routes
+	api := r.Group("/api/v1")
+	{
+		// Public routes
+		profiles := api.Group("/profiles")
+		{
+			profiles.GET("/:id", profileHandler.GetProfile)
+		}
+
+		// Protected routes
+		protected := api.Group("/profiles")
+		protected.Use(AuthMiddleware(verifier))
+		{
+			protected.POST("/", profileHandler.CreateProfile)
+			protected.PUT("/:id", profileHandler.UpdateProfile)
+			protected.DELETE("/:id", profileHandler.DeleteProfile)
+		}
+	}
+
+	return r
+}
@@ -0,0 +1,23 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Profile write operations
+const (
+	OperationCreate = "create"
+	OperationUpdate = "update"
+	OperationDelete = "delete"
+)
+
+var profileWrites = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "profile_writes_total",
+	Help: "Profiles created, updated and deleted, by operation.",
+}, []string{"operation"})
+
+// RecordProfileWrite counts a successful profile write
+func RecordProfileWrite(operation string) {
+	profileWrites.WithLabelValues(operation).Inc()
+}
@@ -1,11 +1,10 @@
 package repository
 
 import (
+	"context"
 	"fmt"
-	"log"
 
 	"github.com/tanerincode/e2e-profile/internal/config"
-	"github.com/tanerincode/e2e-profile/internal/model"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -20,11 +19,23 @@ func NewDB(cfg *config.Config) (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	// Run migrations
-	err = db.AutoMigrate(&model.ProfileData{})
+	return db, nil
+}
+
+// PingDB checks that the database can be reached
+func PingDB(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
 	if err != nil {
-		log.Printf("Warning: Failed to run migrations: %v", err)
+		return fmt.Errorf("failed to get database handle: %w", err)
 	}
+	return sqlDB.PingContext(ctx)
+}
 
-	return db, nil
+// CloseDB closes the connection pool behind db
+func CloseDB(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database handle: %w", err)
+	}
+	return sqlDB.Close()
 }
\ No newline at end of file
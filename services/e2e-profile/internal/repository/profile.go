@@ -9,6 +9,9 @@ import (
 	"gorm.io/gorm"
 )
 
+// ErrProfileNotFound is returned when no profile matches the lookup
+var ErrProfileNotFound = errors.New("profile not found")
+
 // ProfileRepository implements the ProfileRepository interface
 type profileRepository struct {
 	db *gorm.DB
@@ -32,7 +35,7 @@ func (r *profileRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.P
 	err := r.db.WithContext(ctx).Where("id = ?", id).First(&profile).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("profile not found")
+			return nil, ErrProfileNotFound
 		}
 		return nil, err
 	}
@@ -45,7 +48,7 @@ func (r *profileRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (
 	err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&profile).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("profile not found")
+			return nil, ErrProfileNotFound
 		}
 		return nil, err
 	}
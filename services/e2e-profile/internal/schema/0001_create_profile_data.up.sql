@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS profile_data (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    bio TEXT,
+    avatar VARCHAR(255),
+    interests TEXT[],
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_profile_data_user_id ON profile_data(user_id);
+CREATE INDEX IF NOT EXISTS idx_profile_data_deleted_at ON profile_data(deleted_at);
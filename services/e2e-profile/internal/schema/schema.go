@@ -0,0 +1,9 @@
+// Package schema holds the versioned SQL migrations for e2e-profile's tables
+package schema
+
+import "embed"
+
+// Migrations holds the migration files, applied by the shared migrator
+//
+//go:embed *.sql
+var Migrations embed.FS
@@ -4,13 +4,20 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/tanerincode/e2e-profile/internal/auth"
+	"github.com/tanerincode/e2e-profile/internal/grpc/client"
 	"github.com/tanerincode/e2e-profile/internal/model"
 )
 
 // ProfileServiceInterface defines the interface for profile-related operations
 type ProfileServiceInterface interface {
 	GetProfile(ctx context.Context, id uuid.UUID) (*model.UserProfile, error)
-	CreateProfile(ctx context.Context, profile *model.ProfileData) error
-	UpdateProfile(ctx context.Context, profile *model.ProfileData) error
-	DeleteProfile(ctx context.Context, id uuid.UUID) error
+	CreateProfile(ctx context.Context, caller *auth.Identity, profile *model.ProfileData) error
+	UpdateProfile(ctx context.Context, caller *auth.Identity, profile *model.ProfileData) error
+	DeleteProfile(ctx context.Context, caller *auth.Identity, id uuid.UUID) error
+}
+
+// UserLookup fetches user accounts from the auth service
+type UserLookup interface {
+	GetUser(ctx context.Context, id uuid.UUID) (*client.User, error)
 }
\ No newline at end of file
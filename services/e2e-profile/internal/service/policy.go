@@ -0,0 +1,39 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/tanerincode/e2e-profile/internal/auth"
+)
+
+// PermissionProfilesManage lets the caller change and delete any profile. The
+// auth service grants it with the admin role.
+const PermissionProfilesManage = "profiles:manage"
+
+var (
+	// ErrForbidden is returned when the caller may not change a profile
+	ErrForbidden = errors.New("not allowed to modify this profile")
+	// ErrProfileNotFound is returned when the profile to change doesn't exist
+	ErrProfileNotFound = errors.New("profile not found")
+	// ErrUserNotFound is returned when the auth service has no user with the ID
+	ErrUserNotFound = errors.New("user not found")
+)
+
+// authorizeProfileChange checks that the caller may change a profile owned by
+// ownerID. Users may only change their own profile unless they were granted
+// PermissionProfilesManage.
+func authorizeProfileChange(caller *auth.Identity, ownerID uuid.UUID) error {
+	if caller == nil {
+		return ErrForbidden
+	}
+	if caller.HasPermission(PermissionProfilesManage) {
+		return nil
+	}
+
+	callerID, err := uuid.Parse(caller.UserID)
+	if err != nil || callerID != ownerID {
+		return ErrForbidden
+	}
+	return nil
+}
@@ -2,29 +2,27 @@ package service
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
 
 	"github.com/google/uuid"
-	"github.com/tanerincode/e2e-profile/internal/config"
+	"github.com/tanerincode/e2e-profile/internal/auth"
+	"github.com/tanerincode/e2e-profile/internal/grpc/client"
+	"github.com/tanerincode/e2e-profile/internal/metrics"
 	"github.com/tanerincode/e2e-profile/internal/model"
 	"github.com/tanerincode/e2e-profile/internal/repository"
 )
 
 // ProfileService handles profile data operations
 type ProfileService struct {
-	client     *http.Client
-	config     *config.Config
+	users       UserLookup
 	profileRepo repository.ProfileRepository
 }
 
 // NewProfileService creates a new instance of ProfileService
-func NewProfileService(cfg *config.Config, profileRepo repository.ProfileRepository) *ProfileService {
+func NewProfileService(users UserLookup, profileRepo repository.ProfileRepository) *ProfileService {
 	return &ProfileService{
-		client:     &http.Client{},
-		config:     cfg,
+		users:       users,
 		profileRepo: profileRepo,
 	}
 }
@@ -33,14 +31,17 @@ func NewProfileService(cfg *config.Config, profileRepo repository.ProfileReposit
 // It combines data from our database with data from the auth service
 func (s *ProfileService) GetProfile(ctx context.Context, id uuid.UUID) (*model.UserProfile, error) {
 	// First, get user data from auth service
-	authUserData, err := s.getUserFromAuthService(ctx, id)
+	authUserData, err := s.users.GetUser(ctx, id)
 	if err != nil {
+		if errors.Is(err, client.ErrUserNotFound) {
+			return nil, ErrUserNotFound
+		}
 		return nil, fmt.Errorf("failed to get user data: %w", err)
 	}
 	
 	// Second, get profile data from our database
 	profileData, err := s.profileRepo.GetByUserID(ctx, id)
-	if err != nil {
+	if errors.Is(err, repository.ErrProfileNotFound) {
 		// If profile doesn't exist, that's ok - we'll just return user data
 		// with a nil profile
 		return &model.UserProfile{
@@ -53,6 +54,9 @@ func (s *ProfileService) GetProfile(ctx context.Context, id uuid.UUID) (*model.U
 			Profile:   nil,
 		}, nil
 	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile data: %w", err)
+	}
 	
 	// Combine data and return
 	return &model.UserProfile{
@@ -66,45 +70,75 @@ func (s *ProfileService) GetProfile(ctx context.Context, id uuid.UUID) (*model.U
 	}, nil
 }
 
-// CreateProfile creates a new profile
-func (s *ProfileService) CreateProfile(ctx context.Context, profile *model.ProfileData) error {
-	return s.profileRepo.Create(ctx, profile)
-}
+// CreateProfile creates a new profile owned by the caller. Only callers
+// allowed to manage every profile may create one for another user.
+func (s *ProfileService) CreateProfile(ctx context.Context, caller *auth.Identity, profile *model.ProfileData) error {
+	if caller == nil {
+		return ErrForbidden
+	}
+	if profile.UserID == uuid.Nil {
+		callerID, err := uuid.Parse(caller.UserID)
+		if err != nil {
+			return ErrForbidden
+		}
+		profile.UserID = callerID
+	}
 
-// UpdateProfile updates an existing profile
-func (s *ProfileService) UpdateProfile(ctx context.Context, profile *model.ProfileData) error {
-	return s.profileRepo.Update(ctx, profile)
+	if err := authorizeProfileChange(caller, profile.UserID); err != nil {
+		return err
+	}
+	if err := s.profileRepo.Create(ctx, profile); err != nil {
+		return err
+	}
+	metrics.RecordProfileWrite(metrics.OperationCreate)
+	return nil
 }
 
-// DeleteProfile deletes a profile
-func (s *ProfileService) DeleteProfile(ctx context.Context, id uuid.UUID) error {
-	return s.profileRepo.Delete(ctx, id)
+// UpdateProfile updates an existing profile if the caller may change it. The
+// profile keeps its owner whatever the request says.
+func (s *ProfileService) UpdateProfile(ctx context.Context, caller *auth.Identity, profile *model.ProfileData) error {
+	existing, err := s.getExisting(ctx, profile.ID)
+	if err != nil {
+		return err
+	}
+	if err := authorizeProfileChange(caller, existing.UserID); err != nil {
+		return err
+	}
+
+	profile.UserID = existing.UserID
+	profile.CreatedAt = existing.CreatedAt
+	if err := s.profileRepo.Update(ctx, profile); err != nil {
+		return fmt.Errorf("failed to update profile: %w", err)
+	}
+	metrics.RecordProfileWrite(metrics.OperationUpdate)
+	return nil
 }
 
-// Helper method to get user data from auth service
-func (s *ProfileService) getUserFromAuthService(ctx context.Context, id uuid.UUID) (*model.UserProfile, error) {
-	url := fmt.Sprintf("%s/api/v1/user/profile/%s", s.config.AuthServiceURL, id)
-	
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+// DeleteProfile deletes a profile if the caller may change it
+func (s *ProfileService) DeleteProfile(ctx context.Context, caller *auth.Identity, id uuid.UUID) error {
+	existing, err := s.getExisting(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return err
 	}
-	
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+	if err := authorizeProfileChange(caller, existing.UserID); err != nil {
+		return err
 	}
-	defer resp.Body.Close()
-	
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to fetch profile (status %d): %s", resp.StatusCode, string(body))
+	if err := s.profileRepo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete profile: %w", err)
 	}
-	
-	var profile model.UserProfile
-	if err := json.NewDecoder(resp.Body).Decode(&profile); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	metrics.RecordProfileWrite(metrics.OperationDelete)
+	return nil
+}
+
+// getExisting loads the profile a change is for. Only a missing profile is
+// reported as ErrProfileNotFound; a failing database must not look like one.
+func (s *ProfileService) getExisting(ctx context.Context, id uuid.UUID) (*model.ProfileData, error) {
+	existing, err := s.profileRepo.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrProfileNotFound) {
+		return nil, ErrProfileNotFound
 	}
-	
-	return &profile, nil
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile: %w", err)
+	}
+	return existing, nil
 }
\ No newline at end of file
@@ -0,0 +1,37 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler serves the liveness and readiness probes over HTTP
+type Handler struct {
+	checks *Registry
+}
+
+// NewHandler creates a handler that reports readiness from checks
+func NewHandler(checks *Registry) *Handler {
+	return &Handler{
+		checks: checks,
+	}
+}
+
+// Livez reports that the process is up. It doesn't look at dependencies, so
+// an outage of one doesn't get every replica restarted.
+func (h *Handler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": StatusHealthy,
+	})
+}
+
+// Readyz runs every dependency check and answers 503 if any of them fails
+func (h *Handler) Readyz(c *gin.Context) {
+	report := h.checks.Run(c.Request.Context())
+	if !report.Healthy() {
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
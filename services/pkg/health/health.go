@@ -0,0 +1,104 @@
+// Package health runs the dependency checks behind the liveness and
+// readiness probes of both services
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Status values reported for the process and for each check
+const (
+	StatusHealthy   = "healthy"
+	StatusUnhealthy = "unhealthy"
+)
+
+// Check reports whether a dependency is usable. It should give up when ctx
+// is done.
+type Check func(ctx context.Context) error
+
+// Result is the outcome of a single check
+type Result struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of every registered check. Status is healthy only
+// when every check passed.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Healthy reports whether every check passed
+func (r *Report) Healthy() bool {
+	return r.Status == StatusHealthy
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Registry holds the dependency checks that decide whether the process is
+// ready to serve
+type Registry struct {
+	timeout time.Duration
+	checks  []namedCheck
+}
+
+// NewRegistry creates an empty registry. Each check gets up to timeout to
+// finish.
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{timeout: timeout}
+}
+
+// Register adds a check under name. Checks are registered during startup,
+// before Run is called.
+func (r *Registry) Register(name string, check Check) {
+	r.checks = append(r.checks, namedCheck{name: name, check: check})
+}
+
+// Run runs every check concurrently and reports their outcomes
+func (r *Registry) Run(ctx context.Context) *Report {
+	report := &Report{Status: StatusHealthy, Checks: make(map[string]Result, len(r.checks))}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for _, c := range r.checks {
+		wg.Add(1)
+		go func(c namedCheck) {
+			defer wg.Done()
+			result := r.run(ctx, c.check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[c.name] = result
+			if result.Status != StatusHealthy {
+				report.Status = StatusUnhealthy
+			}
+		}(c)
+	}
+	wg.Wait()
+	return report
+}
+
+// run runs a single check within the registry's timeout
+func (r *Registry) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	result := Result{
+		Status:    StatusHealthy,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusUnhealthy
+		result.Error = err.Error()
+	}
+	return result
+}
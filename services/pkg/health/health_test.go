@@ -0,0 +1,123 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// slowCheck passes after delay unless ctx is done first
+func slowCheck(delay time.Duration) Check {
+	return func(ctx context.Context) error {
+		select {
+		case <-time.After(delay):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func TestRegistryRun(t *testing.T) {
+	registry := NewRegistry(50 * time.Millisecond)
+	registry.Register("database", slowCheck(20*time.Millisecond))
+	registry.Register("cache", slowCheck(20*time.Millisecond))
+	registry.Register("auth", func(ctx context.Context) error { return errors.New("connection refused") })
+	// Ignores everything but its context, so only the timeout ends it
+	registry.Register("stuck", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	start := time.Now()
+	report := registry.Run(context.Background())
+	elapsed := time.Since(start)
+
+	if report.Healthy() || report.Status != StatusUnhealthy {
+		t.Errorf("got status %q, want %q", report.Status, StatusUnhealthy)
+	}
+	// Checks run concurrently, so the slowest one bounds the whole run
+	if elapsed > time.Second {
+		t.Errorf("Run took %v, want about the 50ms timeout", elapsed)
+	}
+
+	for _, name := range []string{"database", "cache"} {
+		result := report.Checks[name]
+		if result.Status != StatusHealthy || result.Error != "" {
+			t.Errorf("%s: got %+v, want healthy", name, result)
+		}
+		if result.LatencyMS < 20 {
+			t.Errorf("%s: got latency %vms, want at least 20ms", name, result.LatencyMS)
+		}
+	}
+	if result := report.Checks["auth"]; result.Status != StatusUnhealthy || result.Error != "connection refused" {
+		t.Errorf("auth: got %+v, want unhealthy with the check's error", result)
+	}
+	stuck := report.Checks["stuck"]
+	if stuck.Status != StatusUnhealthy || stuck.Error != context.DeadlineExceeded.Error() {
+		t.Errorf("stuck: got %+v, want unhealthy with %v", stuck, context.DeadlineExceeded)
+	}
+	if stuck.LatencyMS < 50 {
+		t.Errorf("stuck: got latency %vms, want at least the 50ms timeout", stuck.LatencyMS)
+	}
+}
+
+func TestRegistryRunWithoutChecks(t *testing.T) {
+	report := NewRegistry(time.Second).Run(context.Background())
+	if !report.Healthy() || len(report.Checks) != 0 {
+		t.Errorf("got %+v, want healthy without checks", report)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	serve := func(registry *Registry, target string) *httptest.ResponseRecorder {
+		handler := NewHandler(registry)
+		r := gin.New()
+		r.GET("/livez", handler.Livez)
+		r.GET("/readyz", handler.Readyz)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	ready := NewRegistry(time.Second)
+	ready.Register("database", slowCheck(time.Millisecond))
+	rec := serve(ready, "/readyz")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/readyz: got status %d, want %d", rec.Code, http.StatusOK)
+	}
+	var report Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("failed to decode report: %v", err)
+	}
+	if result, ok := report.Checks["database"]; !ok || result.Status != StatusHealthy || result.LatencyMS <= 0 {
+		t.Errorf("/readyz reported database as %+v, want healthy with its latency", result)
+	}
+	if !strings.Contains(rec.Body.String(), `"latency_ms"`) {
+		t.Errorf("/readyz body %s doesn't report latency_ms", rec.Body)
+	}
+
+	// A failing dependency makes the replica unready but not dead
+	failing := NewRegistry(20 * time.Millisecond)
+	failing.Register("database", slowCheck(time.Second))
+	rec = serve(failing, "/readyz")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz with a timed out check: got status %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if !strings.Contains(rec.Body.String(), context.DeadlineExceeded.Error()) {
+		t.Errorf("/readyz body %s doesn't say the check timed out", rec.Body)
+	}
+	if rec = serve(failing, "/livez"); rec.Code != http.StatusOK {
+		t.Errorf("/livez with a failing check: got status %d, want %d", rec.Code, http.StatusOK)
+	}
+}
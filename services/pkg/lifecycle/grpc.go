@@ -0,0 +1,42 @@
+package lifecycle
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc"
+)
+
+// grpcServer serves a grpc.Server on a listener opened in advance
+type grpcServer struct {
+	server   *grpc.Server
+	listener net.Listener
+}
+
+// GRPCServer adapts server to the Server interface. Shutdown lets in-flight
+// calls finish with GracefulStop and cancels the rest once the drain
+// timeout passes.
+func GRPCServer(server *grpc.Server, listener net.Listener) Server {
+	return &grpcServer{server: server, listener: listener}
+}
+
+func (s *grpcServer) Serve() error {
+	return s.server.Serve(s.listener)
+}
+
+func (s *grpcServer) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		<-done
+		return ctx.Err()
+	}
+}
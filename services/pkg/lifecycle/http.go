@@ -0,0 +1,31 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// httpServer serves an http.Server on a listener opened in advance
+type httpServer struct {
+	server   *http.Server
+	listener net.Listener
+}
+
+// HTTPServer adapts server to the Server interface. Shutdown waits for
+// in-flight requests to complete.
+func HTTPServer(server *http.Server, listener net.Listener) Server {
+	return &httpServer{server: server, listener: listener}
+}
+
+func (s *httpServer) Serve() error {
+	if err := s.server.Serve(s.listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *httpServer) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
@@ -0,0 +1,59 @@
+package lifecycle
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// job runs a task at a fixed interval
+type job struct {
+	name     string
+	interval time.Duration
+	task     func(ctx context.Context) error
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// Job adapts a task run every interval to the Server interface. A failed run
+// is logged and the task runs again on the next tick. Shutdown cancels a run
+// in progress and waits for it to return.
+func Job(name string, interval time.Duration, task func(ctx context.Context) error) Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &job{
+		name:     name,
+		interval: interval,
+		task:     task,
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+}
+
+func (j *job) Serve() error {
+	defer close(j.done)
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-j.ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := j.task(j.ctx); err != nil && j.ctx.Err() == nil {
+				log.Printf("%s failed: %v", j.name, err)
+			}
+		}
+	}
+}
+
+func (j *job) Shutdown(ctx context.Context) error {
+	j.cancel()
+	select {
+	case <-j.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,82 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestJobRunsUntilShutdown(t *testing.T) {
+	var runs atomic.Int32
+	job := Job("Test job", 5*time.Millisecond, func(ctx context.Context) error {
+		// A failed run doesn't stop the job
+		if runs.Add(1) == 1 {
+			return errors.New("first run fails")
+		}
+		return nil
+	})
+
+	served := make(chan error, 1)
+	go func() { served <- job.Serve() }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runs.Load() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("job ran %d times, want at least 3", runs.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := job.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve returned %v after Shutdown, want nil", err)
+	}
+}
+
+func TestJobShutdownCancelsRun(t *testing.T) {
+	running := make(chan struct{})
+	var once sync.Once
+	var cancelled atomic.Bool
+	job := Job("Test job", time.Millisecond, func(ctx context.Context) error {
+		once.Do(func() { close(running) })
+		<-ctx.Done()
+		cancelled.Store(true)
+		return ctx.Err()
+	})
+	go job.Serve()
+
+	<-running
+	if err := job.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	// Shutdown only returns once the run has seen the cancellation
+	if !cancelled.Load() {
+		t.Errorf("Shutdown returned before the run was cancelled")
+	}
+}
+
+func TestJobShutdownGivesUpOnStuckRun(t *testing.T) {
+	running := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	var once sync.Once
+	job := Job("Test job", time.Millisecond, func(ctx context.Context) error {
+		once.Do(func() { close(running) })
+		// Ignores the cancellation
+		<-release
+		return nil
+	})
+	go job.Serve()
+
+	<-running
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := job.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
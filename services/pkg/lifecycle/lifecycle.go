@@ -0,0 +1,145 @@
+// Package lifecycle runs a service's servers and drains them on shutdown
+package lifecycle
+
+import (
+	"context"
+	"log"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Exit codes returned by Run
+const (
+	// ExitOK means the process was asked to stop and shut down cleanly
+	ExitOK = 0
+	// ExitServerFailed means a server stopped without being asked to
+	ExitServerFailed = 1
+	// ExitShutdownFailed means a server didn't drain within the timeout or a
+	// resource failed to close
+	ExitShutdownFailed = 2
+)
+
+// Server is a long-running server the Manager starts and stops
+type Server interface {
+	// Serve blocks until the server stops. It returns nil when the server
+	// was stopped by Shutdown.
+	Serve() error
+	// Shutdown stops accepting new work and waits for in-flight work to
+	// finish, giving up when ctx is done
+	Shutdown(ctx context.Context) error
+}
+
+type namedServer struct {
+	name   string
+	server Server
+}
+
+type namedCloser struct {
+	name  string
+	close func() error
+}
+
+// Manager runs the servers of a process until SIGTERM or SIGINT, then drains
+// them and closes the resources they used
+type Manager struct {
+	timeout time.Duration
+	servers []namedServer
+	closers []namedCloser
+}
+
+// New creates a Manager that gives servers up to timeout to drain
+func New(timeout time.Duration) *Manager {
+	return &Manager{timeout: timeout}
+}
+
+// AddServer registers a server to start with Run
+func (m *Manager) AddServer(name string, server Server) {
+	m.servers = append(m.servers, namedServer{name: name, server: server})
+}
+
+// AddCloser registers a resource to close once every server has stopped.
+// Resources are closed in the reverse order they were added.
+func (m *Manager) AddCloser(name string, close func() error) {
+	m.closers = append(m.closers, namedCloser{name: name, close: close})
+}
+
+// Run starts every server and blocks until a signal arrives or a server
+// stops on its own. It then shuts every server down, closes the resources
+// and returns the code the process should exit with.
+func (m *Manager) Run() int {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	type result struct {
+		name string
+		err  error
+	}
+	stopped := make(chan result, len(m.servers))
+	for _, s := range m.servers {
+		go func(s namedServer) {
+			stopped <- result{name: s.name, err: s.server.Serve()}
+		}(s)
+	}
+
+	code := ExitOK
+	select {
+	case <-ctx.Done():
+		log.Printf("Shutting down, draining for up to %v", m.timeout)
+	case r := <-stopped:
+		log.Printf("%s server stopped unexpectedly: %v", r.name, r.err)
+		code = ExitServerFailed
+	}
+	// A second signal kills the process instead of waiting for the drain
+	stop()
+
+	if !m.shutdown() && code == ExitOK {
+		code = ExitShutdownFailed
+	}
+	if !m.close() && code == ExitOK {
+		code = ExitShutdownFailed
+	}
+	return code
+}
+
+// shutdown drains every server at once and reports whether all of them
+// finished in time
+func (m *Manager) shutdown() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	ok := true
+	for _, s := range m.servers {
+		wg.Add(1)
+		go func(s namedServer) {
+			defer wg.Done()
+			if err := s.server.Shutdown(ctx); err != nil {
+				log.Printf("Failed to shut down %s server: %v", s.name, err)
+				mu.Lock()
+				ok = false
+				mu.Unlock()
+				return
+			}
+			log.Printf("%s server stopped", s.name)
+		}(s)
+	}
+	wg.Wait()
+	return ok
+}
+
+// close closes every resource in reverse order and reports whether all of
+// them closed cleanly
+func (m *Manager) close() bool {
+	ok := true
+	for i := len(m.closers) - 1; i >= 0; i-- {
+		c := m.closers[i]
+		if err := c.close(); err != nil {
+			log.Printf("Failed to close %s: %v", c.name, err)
+			ok = false
+		}
+	}
+	return ok
+}
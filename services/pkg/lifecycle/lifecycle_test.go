@@ -0,0 +1,177 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fakeServer serves until it is shut down, or fails right away with
+// serveErr. A hanging server never finishes draining.
+type fakeServer struct {
+	started  chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+	serveErr error
+	hanging  bool
+}
+
+func newFakeServer() *fakeServer {
+	return &fakeServer{started: make(chan struct{}), stopped: make(chan struct{})}
+}
+
+func (s *fakeServer) Serve() error {
+	close(s.started)
+	if s.serveErr != nil {
+		return s.serveErr
+	}
+	<-s.stopped
+	return nil
+}
+
+func (s *fakeServer) Shutdown(ctx context.Context) error {
+	if s.hanging {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	s.stopOnce.Do(func() { close(s.stopped) })
+	return nil
+}
+
+// run runs m and, once every server has started, sends the process SIGTERM
+// if terminate is set. It returns the exit code.
+func run(t *testing.T, m *Manager, servers []*fakeServer, terminate bool) int {
+	t.Helper()
+
+	code := make(chan int, 1)
+	go func() { code <- m.Run() }()
+
+	// Run is listening for signals once the servers have been started
+	for _, s := range servers {
+		<-s.started
+	}
+	if terminate {
+		if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+			t.Fatalf("failed to send SIGTERM: %v", err)
+		}
+	}
+
+	select {
+	case c := <-code:
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run didn't return")
+		return -1
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name      string
+		failing   bool
+		hanging   bool
+		closerErr error
+		terminate bool
+		want      int
+	}{
+		{name: "clean shutdown", terminate: true, want: ExitOK},
+		{name: "server fails", failing: true, want: ExitServerFailed},
+		{name: "server fails and a resource fails to close", failing: true, closerErr: errors.New("close failed"), want: ExitServerFailed},
+		{name: "server doesn't drain in time", hanging: true, terminate: true, want: ExitShutdownFailed},
+		{name: "resource fails to close", closerErr: errors.New("close failed"), terminate: true, want: ExitShutdownFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			healthy := newFakeServer()
+			other := newFakeServer()
+			other.hanging = tt.hanging
+			if tt.failing {
+				other.serveErr = errors.New("listener closed")
+			}
+
+			var closed []string
+			m := New(50 * time.Millisecond)
+			m.AddServer("healthy", healthy)
+			m.AddServer("other", other)
+			m.AddCloser("database", func() error {
+				closed = append(closed, "database")
+				return nil
+			})
+			m.AddCloser("tracing", func() error {
+				closed = append(closed, "tracing")
+				return tt.closerErr
+			})
+
+			if got := run(t, m, []*fakeServer{healthy, other}, tt.terminate); got != tt.want {
+				t.Errorf("got exit code %d, want %d", got, tt.want)
+			}
+
+			// Everything is stopped and closed, whatever went wrong
+			select {
+			case <-healthy.stopped:
+			default:
+				t.Errorf("healthy server wasn't shut down")
+			}
+			if want := []string{"tracing", "database"}; !slices.Equal(closed, want) {
+				t.Errorf("closed %v, want %v", closed, want)
+			}
+		})
+	}
+}
+
+func TestHTTPServerDrainsInFlightRequests(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	received := make(chan struct{})
+	release := make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(received)
+		<-release
+		io.WriteString(w, "done")
+	})}
+
+	m := New(5 * time.Second)
+	m.AddServer("HTTP", HTTPServer(server, listener))
+	code := make(chan int, 1)
+	go func() { code <- m.Run() }()
+
+	type response struct {
+		body string
+		err  error
+	}
+	responses := make(chan response, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			responses <- response{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		responses <- response{body: string(body), err: err}
+	}()
+
+	// Shut down while the request is being handled, then let it finish
+	<-received
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatalf("failed to send SIGTERM: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	if r := <-responses; r.err != nil || r.body != "done" {
+		t.Errorf("in-flight request got %q, %v, want it to complete", r.body, r.err)
+	}
+	if got := <-code; got != ExitOK {
+		t.Errorf("got exit code %d, want %d", got, ExitOK)
+	}
+}
@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestHTTPMiddlewareLabelsRoutesByTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(HTTPMiddleware())
+	r.GET("/test/users/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/metrics", gin.WrapH(Handler()))
+
+	before := testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, "/test/users/:id", "200"))
+	beforeUnmatched := testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, "unmatched", "404"))
+
+	for _, target := range []string{"/test/users/1", "/test/users/2", "/test/nothing-here", "/test/.env"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, "/test/users/:id", "200")) - before; got != 2 {
+		t.Errorf("got %v requests labelled with the route pattern, want 2", got)
+	}
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, "unmatched", "404")) - beforeUnmatched; got != 2 {
+		t.Errorf("got %v requests labelled unmatched, want 2", got)
+	}
+
+	// Neither request paths nor unmatched paths become series of their own
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	if !strings.Contains(body, `http_request_duration_seconds_count{method="GET",route="/test/users/:id",status="200"}`) {
+		t.Errorf("/metrics has no latency for the route pattern")
+	}
+	for _, path := range []string{"/test/users/1", "/test/nothing-here", "/test/.env"} {
+		if strings.Contains(body, `route="`+path+`"`) {
+			t.Errorf("/metrics has a series for the path %s", path)
+		}
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.TestService/Get"}
+
+	before := testutil.ToFloat64(grpcServerHandled.WithLabelValues("test.TestService", "Get", "OK"))
+	beforeNotFound := testutil.ToFloat64(grpcServerHandled.WithLabelValues("test.TestService", "Get", "NotFound"))
+
+	ok := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	notFound := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	if _, err := interceptor(context.Background(), nil, info, ok); err != nil {
+		t.Fatalf("interceptor failed: %v", err)
+	}
+	// The handler's error reaches the caller unchanged
+	if _, err := interceptor(context.Background(), nil, info, notFound); status.Code(err) != codes.NotFound {
+		t.Fatalf("got %v, want %v", err, codes.NotFound)
+	}
+
+	if got := testutil.ToFloat64(grpcServerHandled.WithLabelValues("test.TestService", "Get", "OK")) - before; got != 1 {
+		t.Errorf("got %v OK calls, want 1", got)
+	}
+	if got := testutil.ToFloat64(grpcServerHandled.WithLabelValues("test.TestService", "Get", "NotFound")) - beforeNotFound; got != 1 {
+		t.Errorf("got %v NotFound calls, want 1", got)
+	}
+}
+
+func TestSplitMethod(t *testing.T) {
+	tests := []struct {
+		fullMethod  string
+		wantService string
+		wantMethod  string
+	}{
+		{fullMethod: "/auth.AuthService/ValidateToken", wantService: "auth.AuthService", wantMethod: "ValidateToken"},
+		{fullMethod: "auth.AuthService/ValidateToken", wantService: "auth.AuthService", wantMethod: "ValidateToken"},
+		{fullMethod: "garbage", wantService: "unknown", wantMethod: "unknown"},
+	}
+	for _, tt := range tests {
+		if service, method := splitMethod(tt.fullMethod); service != tt.wantService || method != tt.wantMethod {
+			t.Errorf("splitMethod(%q) = %q, %q, want %q, %q", tt.fullMethod, service, method, tt.wantService, tt.wantMethod)
+		}
+	}
+}
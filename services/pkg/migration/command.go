@@ -0,0 +1,73 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"text/tabwriter"
+
+	"gorm.io/gorm"
+)
+
+// RunCommand implements the "migrate up|down|status" subcommand of a service
+// with the migrations in files. args are the arguments after "migrate";
+// results are written to out.
+func RunCommand(ctx context.Context, db *gorm.DB, files fs.FS, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: migrate up|down|status")
+	}
+
+	migrator, err := New(db, files)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	switch args[0] {
+	case "up":
+		if err := migrator.Up(ctx); err != nil {
+			return fmt.Errorf("failed to apply migrations: %w", err)
+		}
+		fmt.Fprintln(out, "Migrations applied")
+	case "down":
+		if err := migrator.Down(ctx); err != nil {
+			return fmt.Errorf("failed to roll back migration: %w", err)
+		}
+		fmt.Fprintln(out, "Rolled back the latest migration")
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read migration status: %w", err)
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down or status", args[0])
+	}
+	return nil
+}
+
+// Ensure applies pending migrations if autoMigrate is set, then returns
+// ErrSchemaBehind if the schema is still behind the migrations in files.
+// Services call it on startup and refuse to start on an error.
+func Ensure(ctx context.Context, db *gorm.DB, files fs.FS, autoMigrate bool) error {
+	migrator, err := New(db, files)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	if autoMigrate {
+		if err := migrator.Up(ctx); err != nil {
+			return fmt.Errorf("failed to apply migrations: %w", err)
+		}
+	}
+	return migrator.EnsureCurrent(ctx)
+}
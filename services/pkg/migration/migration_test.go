@@ -0,0 +1,136 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testMigrations creates two tables, one per migration
+var testMigrations = fstest.MapFS{
+	"0001_create_users.up.sql":      {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY)")},
+	"0001_create_users.down.sql":    {Data: []byte("DROP TABLE users")},
+	"0002_create_profiles.up.sql":   {Data: []byte("CREATE TABLE profiles (id INTEGER PRIMARY KEY)")},
+	"0002_create_profiles.down.sql": {Data: []byte("DROP TABLE profiles")},
+}
+
+// newTestDB opens an empty SQLite database
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// run runs the migrate subcommand and returns what it printed
+func run(t *testing.T, db *gorm.DB, args ...string) string {
+	t.Helper()
+
+	var out strings.Builder
+	if err := RunCommand(context.Background(), db, testMigrations, args, &out); err != nil {
+		t.Fatalf("migrate %s failed: %v", strings.Join(args, " "), err)
+	}
+	return out.String()
+}
+
+// tables reports which of the test tables exist
+func tables(db *gorm.DB) (users, profiles bool) {
+	return db.Migrator().HasTable("users"), db.Migrator().HasTable("profiles")
+}
+
+func TestRunCommand(t *testing.T) {
+	db := newTestDB(t)
+
+	status := run(t, db, "status")
+	if strings.Count(status, "pending") != 2 {
+		t.Errorf("status before up lists %d pending migrations, want 2:\n%s", strings.Count(status, "pending"), status)
+	}
+
+	run(t, db, "up")
+	if users, profiles := tables(db); !users || !profiles {
+		t.Fatalf("after up: users table %v, profiles table %v, want both", users, profiles)
+	}
+	status = run(t, db, "status")
+	if strings.Contains(status, "pending") {
+		t.Errorf("status after up still lists pending migrations:\n%s", status)
+	}
+
+	// Running up again has nothing left to apply
+	run(t, db, "up")
+
+	// Down only rolls back the latest migration
+	run(t, db, "down")
+	if users, profiles := tables(db); !users || profiles {
+		t.Fatalf("after down: users table %v, profiles table %v, want only users", users, profiles)
+	}
+	status = run(t, db, "status")
+	lines := strings.Split(strings.TrimSpace(status), "\n")
+	if len(lines) != 3 || strings.Contains(lines[1], "pending") || !strings.Contains(lines[2], "pending") {
+		t.Errorf("status after down should list 0001 applied and 0002 pending:\n%s", status)
+	}
+}
+
+func TestRunCommandRejectsUsage(t *testing.T) {
+	db := newTestDB(t)
+
+	for _, args := range [][]string{nil, {"sideways"}, {"up", "down"}} {
+		if err := RunCommand(context.Background(), db, testMigrations, args, &strings.Builder{}); err == nil {
+			t.Errorf("migrate %q succeeded, want an error", args)
+		}
+	}
+}
+
+func TestEnsure(t *testing.T) {
+	t.Run("refuses a schema that is behind", func(t *testing.T) {
+		db := newTestDB(t)
+
+		if err := Ensure(context.Background(), db, testMigrations, false); !errors.Is(err, ErrSchemaBehind) {
+			t.Fatalf("got %v, want %v", err, ErrSchemaBehind)
+		}
+
+		// One migration short is still behind
+		run(t, db, "up")
+		run(t, db, "down")
+		err := Ensure(context.Background(), db, testMigrations, false)
+		if !errors.Is(err, ErrSchemaBehind) || !strings.Contains(err.Error(), "2_create_profiles") {
+			t.Fatalf("got %v, want %v naming 2_create_profiles", err, ErrSchemaBehind)
+		}
+	})
+
+	t.Run("applies pending migrations when asked to", func(t *testing.T) {
+		db := newTestDB(t)
+
+		if err := Ensure(context.Background(), db, testMigrations, true); err != nil {
+			t.Fatalf("Ensure failed: %v", err)
+		}
+		if users, profiles := tables(db); !users || !profiles {
+			t.Fatalf("users table %v, profiles table %v, want both", users, profiles)
+		}
+	})
+
+	t.Run("accepts a current schema", func(t *testing.T) {
+		db := newTestDB(t)
+
+		run(t, db, "up")
+		if err := Ensure(context.Background(), db, testMigrations, false); err != nil {
+			t.Fatalf("Ensure failed: %v", err)
+		}
+	})
+}
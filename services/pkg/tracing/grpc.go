@@ -0,0 +1,21 @@
+package tracing
+
+import (
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
+	"google.golang.org/grpc/stats"
+)
+
+// GRPCServerHandler returns a stats handler that starts a server span for
+// every call, continuing the trace in the call metadata. Health checks
+// aren't traced.
+func GRPCServerHandler() stats.Handler {
+	return otelgrpc.NewServerHandler(otelgrpc.WithFilter(filters.Not(filters.HealthCheck())))
+}
+
+// GRPCClientHandler returns a stats handler that starts a client span for
+// every call and sends the trace context along in the call metadata. Health
+// checks aren't traced.
+func GRPCClientHandler() stats.Handler {
+	return otelgrpc.NewClientHandler(otelgrpc.WithFilter(filters.Not(filters.HealthCheck())))
+}
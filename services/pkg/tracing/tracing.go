@@ -0,0 +1,68 @@
+// Package tracing sets up OpenTelemetry for both services and traces their
+// HTTP requests, gRPC calls and database queries
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Exporter names accepted by NewExporter
+const (
+	// ExporterNone records nothing but still passes trace context on
+	ExporterNone = "none"
+	// ExporterOTLP sends spans to an OTLP collector over gRPC. It's set up
+	// with the standard OTEL_EXPORTER_OTLP_* variables, e.g.
+	// OTEL_EXPORTER_OTLP_ENDPOINT.
+	ExporterOTLP = "otlp"
+	// ExporterStdout writes spans to stdout
+	ExporterStdout = "stdout"
+	// ExporterMemory keeps spans in memory, for tests
+	ExporterMemory = "memory"
+)
+
+// NewExporter creates the span exporter called name. ExporterNone returns a
+// nil exporter. The memory exporter is a *tracetest.InMemoryExporter.
+func NewExporter(ctx context.Context, name string) (sdktrace.SpanExporter, error) {
+	switch name {
+	case "", ExporterNone:
+		return nil, nil
+	case ExporterOTLP:
+		return otlptracegrpc.New(ctx)
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case ExporterMemory:
+		return tracetest.NewInMemoryExporter(), nil
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", name)
+	}
+}
+
+// Setup installs a global tracer provider for serviceName and the W3C trace
+// context and baggage propagators. New traces are sampled at sampleRatio;
+// traces started upstream keep the caller's decision. A nil exporter records
+// nothing. Shut the provider down on exit to flush buffered spans.
+func Setup(serviceName string, exporter sdktrace.SpanExporter, sampleRatio float64) *sdktrace.TracerProvider {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	}
+	if exporter != nil {
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+
+	provider := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(provider)
+	return provider
+}